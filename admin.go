@@ -0,0 +1,236 @@
+package zcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// AdminHandler 返回用于查看和管理本节点的 handler，与节点间通讯使用相同的认证（TLS 和 Secret），
+// 两者都未配置时不做任何认证，只应暴露在内部网络中
+// 例如 http.Handle("/admin/", http.StripPrefix("/admin", pool.AdminHandler()))
+// 配置了 Secret 时请求需要使用相同 Secret 的 SignRequest 签名，签名覆盖包含 /admin 前缀的完整路径
+//
+//	GET    /groups                    列出所有 Group
+//	GET    /groups/{group}            查看 Group 的配置和统计数据
+//	DELETE /groups/{group}            清空本节点上 Group 的缓存
+//	GET    /groups/{group}/keys/{key} 查看本节点缓存的值，不会触发加载
+//	DELETE /groups/{group}/keys/{key} 删除 key，与 Group.Remove 相同
+//	GET    /peers?key={key}           查看节点选择状态，指定 key 时同时返回它的所属节点
+func (p *HTTPPool) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /groups", p.adminGroups)
+	mux.HandleFunc("GET /groups/{group}", p.adminGroup)
+	mux.HandleFunc("DELETE /groups/{group}", p.adminClear)
+	mux.HandleFunc("GET /groups/{group}/keys/{key...}", p.adminPeek)
+	mux.HandleFunc("DELETE /groups/{group}/keys/{key...}", p.adminRemove)
+	mux.HandleFunc("GET /peers", p.adminPeers)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer recoverPanic(w, r, p.logger)
+		if err := p.authenticate(w, r); err != nil {
+			httpError(w, err)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// groupConfig Group 的配置
+type groupConfig struct {
+	MainCacheBytes int64   `json:"main_cache_bytes"`
+	HotCacheBytes  int64   `json:"hot_cache_bytes"`
+	HotRate        float64 `json:"hot_rate"`
+	EntryOverhead  int64   `json:"entry_overhead"`
+	TTL            string  `json:"ttl"`
+	Janitor        string  `json:"janitor"`
+	Replicas       int     `json:"replicas"`
+	ReplicaWrites  bool    `json:"replica_writes"`
+	Hedging        bool    `json:"hedging"`
+	HedgeDelay     string  `json:"hedge_delay"`
+	Retries        int     `json:"retries"`
+	RetryBackoff   string  `json:"retry_backoff"`
+}
+
+// groupInfo Group 的配置和统计数据
+type groupInfo struct {
+	Name      string      `json:"name"`
+	Config    groupConfig `json:"config"`
+	Stats     Stats       `json:"stats"`
+	MainCache CacheStats  `json:"main_cache"`
+	HotCache  CacheStats  `json:"hot_cache"`
+}
+
+func (g *Group) info() groupInfo {
+	hedgeDelay := "p95"
+	if g.hedgeDelay > 0 {
+		hedgeDelay = g.hedgeDelay.String()
+	}
+	return groupInfo{
+		Name: g.name,
+		Config: groupConfig{
+			MainCacheBytes: g.mainCache.cacheBytes,
+			HotCacheBytes:  g.hotCache.cacheBytes,
+			HotRate:        g.hotRate,
+			EntryOverhead:  g.mainCache.overhead,
+			TTL:            g.ttl.String(),
+			Janitor:        g.interval.String(),
+			Replicas:       max(g.replicas, 1),
+			ReplicaWrites:  g.replicaWrites,
+			Hedging:        g.hedging,
+			HedgeDelay:     hedgeDelay,
+			Retries:        g.retries,
+			RetryBackoff:   g.retryBackoff.String(),
+		},
+		Stats:     g.Stats(),
+		MainCache: g.CacheStats(MainCache),
+		HotCache:  g.CacheStats(HotCache),
+	}
+}
+
+// pathGroup 按路径中的名称查找 Group，不存在时返回 404
+func pathGroup(w http.ResponseWriter, r *http.Request) *Group {
+	name := r.PathValue("group")
+	g := GetGroup(name)
+	if g == nil {
+		httpError(w, fmt.Errorf("%w: %s", ErrNoSuchGroup, name))
+	}
+	return g
+}
+
+func (p *HTTPPool) adminGroups(w http.ResponseWriter, _ *http.Request) {
+	list := listGroups()
+	names := make([]string, len(list))
+	for i, g := range list {
+		names[i] = g.name
+	}
+	writeJSON(w, names)
+}
+
+func (p *HTTPPool) adminGroup(w http.ResponseWriter, r *http.Request) {
+	if g := pathGroup(w, r); g != nil {
+		writeJSON(w, g.info())
+	}
+}
+
+func (p *HTTPPool) adminClear(w http.ResponseWriter, r *http.Request) {
+	if g := pathGroup(w, r); g != nil {
+		g.Clear()
+		p.logger.Info("admin cleared group", "group", g.name)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// peekResult 查看 key 的结果
+type peekResult struct {
+	Key     string `json:"key"`
+	Cache   string `json:"cache"` // main 或 hot
+	Value   []byte `json:"value"` // base64 编码
+	Version string `json:"version,omitempty"`
+}
+
+func (p *HTTPPool) adminPeek(w http.ResponseWriter, r *http.Request) {
+	g := pathGroup(w, r)
+	if g == nil {
+		return
+	}
+	key := r.PathValue("key")
+	res := peekResult{Key: key, Cache: "main"}
+	v, ok := g.mainCache.peek(key)
+	if !ok {
+		res.Cache = "hot"
+		v, ok = g.hotCache.peek(key)
+	}
+	if !ok {
+		http.Error(w, fmt.Sprintf("key not cached: %s", key), http.StatusNotFound)
+		return
+	}
+	res.Value, res.Version = v.ByteSlice(), v.Version()
+	writeJSON(w, res)
+}
+
+func (p *HTTPPool) adminRemove(w http.ResponseWriter, r *http.Request) {
+	g := pathGroup(w, r)
+	if g == nil {
+		return
+	}
+	key := r.PathValue("key")
+	if err := g.Remove(r.Context(), key); err != nil {
+		httpError(w, err)
+		return
+	}
+	p.logger.Info("admin removed key", "group", g.name, "key", key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ringPeer 节点在节点选择中的状态
+type ringPeer struct {
+	Peer     string `json:"peer"`
+	Self     bool   `json:"self"`
+	Weight   int    `json:"weight"`
+	Healthy  bool   `json:"healthy"`
+	Breaker  string `json:"breaker"`
+	Load     int64  `json:"load"`               // 进行中的请求数，只在有界负载模式下统计
+	MaxLoad  int64  `json:"max_load,omitempty"` // 有界负载模式下允许的最大请求数
+	Trips    int64  `json:"breaker_trips"`
+	Rejected int64  `json:"breaker_rejected"`
+}
+
+// ringInfo 节点选择的快照
+type ringInfo struct {
+	Self      string     `json:"self"`
+	Placement string     `json:"placement"`
+	Peers     []ringPeer `json:"peers"`
+	Key       string     `json:"key,omitempty"`
+	Owner     string     `json:"owner,omitempty"` // key 的所属节点
+}
+
+func (p *HTTPPool) adminPeers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, p.ring(r.URL.Query().Get("key")))
+}
+
+// ring 返回节点选择的快照，key 不为空时同时返回它的所属节点
+func (p *HTTPPool) ring(key string) ringInfo {
+	stats := p.PeerStats()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info := ringInfo{Self: p.self, Peers: []ringPeer{}}
+	if p.placement == nil {
+		return info
+	}
+	info.Placement = fmt.Sprintf("%T", p.placement)
+	// 只有有界负载模式下才统计进行中的请求数
+	loads, _ := p.placement.(interface {
+		Load(node string) int64
+		MaxLoad(node string) int64
+	})
+	if p.opts.LoadFactor <= 0 {
+		loads = nil
+	}
+	for peer, weight := range p.weights {
+		rp := ringPeer{Peer: peer, Self: peer == p.self, Weight: weight, Healthy: true, Breaker: BreakerClosed.String()}
+		if s, ok := stats[peer]; ok {
+			rp.Healthy, rp.Breaker, rp.Trips, rp.Rejected = s.Healthy, s.Breaker.String(), s.Trips, s.Rejected
+		}
+		if loads != nil && rp.Healthy {
+			rp.Load, rp.MaxLoad = loads.Load(peer), loads.MaxLoad(peer)
+		}
+		info.Peers = append(info.Peers, rp)
+	}
+	slices.SortFunc(info.Peers, func(a, b ringPeer) int {
+		return strings.Compare(a.Peer, b.Peer)
+	})
+	if key != "" {
+		info.Key, info.Owner = key, p.placement.Get(key)
+	}
+	return info
+}
+
+// writeJSON 以 JSON 格式写入响应
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
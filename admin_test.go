@@ -0,0 +1,144 @@
+package zcache
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAdminHandler(t *testing.T) {
+	z := NewGroup("admin", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v-" + key), nil
+		}),
+		WithTTL(time.Minute),
+	)
+	_, _ = z.Get("a")
+	_, _ = z.Get("b")
+
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{Secret: []byte("s3cret"), LoadFactor: 0.25})
+	pool.Set("http://self", "http://peer")
+	srv := httptest.NewServer(pool.AdminHandler())
+	defer srv.Close()
+
+	do := func(method, path string, sign bool) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sign {
+			pool.SignRequest(req, nil)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+	decode := func(res *http.Response, v any) {
+		t.Helper()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("expect 200, got %v", res.Status)
+		}
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if res := do(http.MethodGet, "/groups", false); res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unsigned admin requests should be rejected, got %v", res.Status)
+	}
+
+	var names []string
+	decode(do(http.MethodGet, "/groups", true), &names)
+	if !slices.Contains(names, "admin") {
+		t.Fatalf("expect group admin in %v", names)
+	}
+
+	var info groupInfo
+	decode(do(http.MethodGet, "/groups/admin", true), &info)
+	if info.Config.TTL != "1m0s" || info.MainCache.Items != 2 || info.Stats.Gets != 2 {
+		t.Fatalf("unexpected group info %+v", info)
+	}
+	if res := do(http.MethodGet, "/groups/missing", true); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expect 404 for unknown group, got %v", res.Status)
+	}
+
+	// 查看 key 不会触发加载，也不计入统计
+	var peek peekResult
+	decode(do(http.MethodGet, "/groups/admin/keys/a", true), &peek)
+	if string(peek.Value) != "v-a" || peek.Cache != "main" {
+		t.Fatalf("unexpected peek result %+v", peek)
+	}
+	if res := do(http.MethodGet, "/groups/admin/keys/c", true); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expect 404 for an uncached key, got %v", res.Status)
+	}
+	if s := z.Stats(); s.Gets != 2 || s.LocalLoads != 2 {
+		t.Fatalf("peek should not touch the group, got %+v", s)
+	}
+
+	if res := do(http.MethodDelete, "/groups/admin/keys/a", true); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expect 204 for delete, got %v", res.Status)
+	}
+	if _, ok := z.mainCache.peek("a"); ok {
+		t.Fatalf("key a should be deleted")
+	}
+	if res := do(http.MethodDelete, "/groups/admin", true); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expect 204 for clear, got %v", res.Status)
+	}
+	if s := z.CacheStats(MainCache); s.Items != 0 || s.Evictions != 0 {
+		t.Fatalf("clear should empty the cache without counting evictions, got %+v", s)
+	}
+
+	var ring ringInfo
+	decode(do(http.MethodGet, "/peers?key=k", true), &ring)
+	if len(ring.Peers) != 2 || ring.Peers[0].Peer != "http://peer" || !ring.Peers[1].Self || ring.Peers[0].MaxLoad == 0 {
+		t.Fatalf("unexpected ring %+v", ring)
+	}
+	if ring.Owner != "http://peer" && ring.Owner != "http://self" {
+		t.Fatalf("unexpected owner %q", ring.Owner)
+	}
+}
+
+func TestAdminHandlerStripPrefix(t *testing.T) {
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{Secret: []byte("s3cret")})
+	mux := http.NewServeMux()
+	mux.Handle("/admin/", http.StripPrefix("/admin", pool.AdminHandler()))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	// 运维工具只需要相同的 Secret 即可签名
+	signer := NewHTTPPoolOpts("", &HTTPPoolOptions{Secret: []byte("s3cret")})
+
+	for _, tc := range []struct {
+		path   string
+		signed string // 签名使用的地址
+		code   int
+	}{
+		{"/admin/groups", "/admin/groups", http.StatusOK},
+		{"/admin/peers?key=k", "/admin/peers?key=k", http.StatusOK},
+		{"/admin/groups", "/groups", http.StatusUnauthorized},
+		{"/admin/peers?key=k", "/admin/peers?key=other", http.StatusUnauthorized},
+	} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+tc.signed, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer.SignRequest(req, nil)
+		if req.URL, err = req.URL.Parse(tc.path); err != nil {
+			t.Fatal(err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tc.code {
+			t.Fatalf("GET %s signed for %s: expect %d, got %v", tc.path, tc.signed, tc.code, res.Status)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package zcache
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HMAC 签名模式下请求携带的头
+const (
+	timestampHeader = "X-Zcache-Timestamp"
+	signatureHeader = "X-Zcache-Signature"
+)
+
+// defaultSignatureSkew 签名时间与服务端时间允许的最大偏差
+// 服务端不记录已使用的签名，窗口内截获的请求可以被重放，需要防止重放时应同时使用 TLS
+const defaultSignatureSkew = 5 * time.Minute
+
+// ErrUnauthorized 请求没有通过节点间的认证
+var ErrUnauthorized = errors.New("zcache: unauthorized")
+
+// TLSOptions 节点间使用 mTLS 通讯的配置，所有节点的证书由同一组 CA 签发
+type TLSOptions struct {
+	// Certificate 本节点的证书，同时用作服务端证书和请求其他节点时的客户端证书
+	Certificate tls.Certificate
+
+	// CAs 签发节点证书的 CA，用于验证对端的服务端证书和客户端证书
+	CAs *x509.CertPool
+}
+
+// TLSConfig 返回服务端应使用的 TLS 配置，要求并验证对端的客户端证书
+// 例如 srv := &http.Server{Handler: pool, TLSConfig: pool.TLSConfig()}，未配置 TLS 时返回 nil
+func (p *HTTPPool) TLSConfig() *tls.Config {
+	if p.opts.TLS == nil {
+		return nil
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{p.opts.TLS.Certificate},
+		ClientCAs:    p.opts.TLS.CAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// clientTLSConfig 返回请求其他节点时使用的 TLS 配置
+func (p *HTTPPool) clientTLSConfig() *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{p.opts.TLS.Certificate},
+		RootCAs:      p.opts.TLS.CAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// authenticate 验证请求来自其他节点，未配置 TLS 和 Secret 时放行所有请求
+// 需要验证签名时会读取并替换 r.Body，请求体超过 maxRequestBodyBytes 时拒绝请求
+func (p *HTTPPool) authenticate(w http.ResponseWriter, r *http.Request) error {
+	if p.opts.TLS != nil && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+		return fmt.Errorf("%w: client certificate required", ErrUnauthorized)
+	}
+	if len(p.opts.Secret) == 0 {
+		return nil
+	}
+	ts, err := strconv.ParseInt(r.Header.Get(timestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing timestamp", ErrUnauthorized)
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > defaultSignatureSkew || skew < -defaultSignatureSkew {
+		return fmt.Errorf("%w: timestamp out of range", ErrUnauthorized)
+	}
+	sig, err := hex.DecodeString(r.Header.Get(signatureHeader))
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrUnauthorized)
+	}
+	var body []byte
+	if r.Body != nil {
+		if body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)); err != nil {
+			return fmt.Errorf("%w: read body: %w", ErrBadRequest, err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	expect := sign(p.opts.Secret, r.Method, requestTarget(r), ts, body)
+	if !hmac.Equal(sig, expect) {
+		return fmt.Errorf("%w: bad signature", ErrUnauthorized)
+	}
+	return nil
+}
+
+// requestTarget 返回客户端实际请求的路径和查询参数
+// 使用 r.RequestURI 而不是 r.URL，http.StripPrefix 等中间件修改 r.URL 后签名仍然有效
+func requestTarget(r *http.Request) string {
+	if r.RequestURI == "" {
+		return r.URL.RequestURI()
+	}
+	if strings.HasPrefix(r.RequestURI, "/") {
+		return r.RequestURI
+	}
+	// 经过代理时可能是完整的 URL
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		return u.RequestURI()
+	}
+	return r.RequestURI
+}
+
+// SignRequest 按 Secret 为请求添加 X-Zcache-Timestamp 和 X-Zcache-Signature 头，未配置 Secret 时什么也不做
+// 签名覆盖请求方法、包含查询参数的路径、时间戳和请求体的 SHA-256，body 必须与 req 实际发送的请求体相同
+// 节点之间的请求会自动签名，调用 AdminHandler 等接口时需要手动签名，例如
+//
+//	req, _ := http.NewRequest(http.MethodGet, "http://10.0.0.2:8008/admin/groups", nil)
+//	pool.SignRequest(req, nil)
+//	res, err := http.DefaultClient.Do(req)
+func (p *HTTPPool) SignRequest(req *http.Request, body []byte) {
+	if len(p.opts.Secret) == 0 {
+		return
+	}
+	ts := time.Now().Unix()
+	req.Header.Set(timestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(signatureHeader, hex.EncodeToString(sign(p.opts.Secret, req.Method, req.URL.RequestURI(), ts, body)))
+}
+
+// sign 计算 HMAC-SHA256(secret, method \n target \n timestamp \n sha256(body))，target 为包含查询参数的请求路径
+func sign(secret []byte, method, target string, ts int64, body []byte) []byte {
+	sum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	_, _ = fmt.Fprintf(mac, "%s\n%s\n%d\n%x", method, target, ts, sum)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,251 @@
+package zcache
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+	pb "zcache/zcachepb"
+)
+
+// testCA 在测试中签发节点证书的 CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "zcache test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue 签发一张可同时用于服务端和客户端认证的节点证书
+func (ca *testCA) issue(t *testing.T, serial int64) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "zcache peer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestHTTPPoolMutualTLS(t *testing.T) {
+	z := NewGroup("http-mtls", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v-" + key), nil
+		}))
+	ca := newTestCA(t)
+	server := NewHTTPPoolOpts("self", &HTTPPoolOptions{
+		TLS: &TLSOptions{Certificate: ca.issue(t, 2), CAs: ca.pool},
+	})
+	srv := httptest.NewUnstartedServer(server)
+	srv.TLS = server.TLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	pool := NewHTTPPoolOpts("https://self", &HTTPPoolOptions{
+		TLS: &TLSOptions{Certificate: ca.issue(t, 3), CAs: ca.pool},
+	})
+	pool.Set("https://self", srv.URL)
+	key, peer := remoteKey(t, pool)
+	out := &pb.Response{}
+	if err := peer.Get(context.Background(), &pb.Request{Group: z.name, Key: key}, out); err != nil {
+		t.Fatal(err)
+	}
+	if string(out.GetValue()) != "v-"+key {
+		t.Fatalf("unexpected value %q", out.GetValue())
+	}
+
+	// 不带客户端证书或证书由其他 CA 签发时握手失败
+	other := newTestCA(t)
+	for name, certs := range map[string][]tls.Certificate{
+		"no certificate": nil,
+		"unknown issuer": {other.issue(t, 4)},
+	} {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			Certificates: certs,
+			RootCAs:      ca.pool,
+		}}}
+		p := NewHTTPPoolOpts("https://self", &HTTPPoolOptions{Client: client})
+		p.Set("https://self", srv.URL)
+		_, peer := remoteKey(t, p)
+		if err := peer.Get(context.Background(), &pb.Request{Group: z.name, Key: key}, &pb.Response{}); err == nil {
+			t.Fatalf("%s: expect the server to reject the client", name)
+		}
+	}
+}
+
+func TestHTTPPoolTLSWithH2C(t *testing.T) {
+	z := NewGroup("http-mtls-h2c", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v-" + key), nil
+		}))
+	ca := newTestCA(t)
+	server := NewHTTPPoolOpts("self", &HTTPPoolOptions{
+		H2C: true,
+		TLS: &TLSOptions{Certificate: ca.issue(t, 2), CAs: ca.pool},
+	})
+	if protocols := server.Protocols(); !protocols.HTTP1() || !protocols.HTTP2() || !protocols.UnencryptedHTTP2() {
+		t.Fatalf("H2C should add to the default protocols, got %v", protocols)
+	}
+	var major atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		major.Store(int32(r.ProtoMajor))
+		server.ServeHTTP(w, r)
+	}))
+	srv.EnableHTTP2 = true
+	srv.Config.Protocols = server.Protocols()
+	srv.TLS = server.TLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	pool := NewHTTPPoolOpts("https://self", &HTTPPoolOptions{
+		H2C: true,
+		TLS: &TLSOptions{Certificate: ca.issue(t, 3), CAs: ca.pool},
+	})
+	pool.Set("https://self", srv.URL)
+	key, peer := remoteKey(t, pool)
+	out := &pb.Response{}
+	if err := peer.Get(context.Background(), &pb.Request{Group: z.name, Key: key}, out); err != nil {
+		t.Fatal(err)
+	}
+	if string(out.GetValue()) != "v-"+key || major.Load() != 2 {
+		t.Fatalf("expect HTTP/2 over TLS, got %q over HTTP/%d", out.GetValue(), major.Load())
+	}
+}
+
+func TestHTTPPoolSignature(t *testing.T) {
+	z := NewGroup("http-hmac", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v-" + key), nil
+		}))
+	secret := []byte("s3cret")
+	srv := httptest.NewServer(NewHTTPPoolOpts("self", &HTTPPoolOptions{Secret: secret}))
+	defer srv.Close()
+
+	newPeer := func(secret []byte) *httpGetter {
+		p := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{Secret: secret})
+		p.Set("http://self", srv.URL)
+		_, peer := remoteKey(t, p)
+		return peer.(*httpGetter)
+	}
+	ctx := context.Background()
+	if err := newPeer(secret).Set(ctx, &pb.SetRequest{Group: z.name, Key: "k", Value: []byte("signed")}); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := z.mainCache.get("k"); !ok || v.String() != "signed" {
+		t.Fatalf("signed PUT should populate the cache, got %q, %v", v.String(), ok)
+	}
+	for name, peer := range map[string]*httpGetter{
+		"unsigned":     newPeer(nil),
+		"wrong secret": newPeer([]byte("wrong")),
+	} {
+		err := peer.Get(ctx, &pb.Request{Group: z.name, Key: "k"}, &pb.Response{})
+		var se *statusError
+		if !errors.As(err, &se) || se.code != http.StatusUnauthorized {
+			t.Fatalf("%s: expect 401, got %v", name, err)
+		}
+	}
+
+	// 过期的签名被拒绝
+	peer := newPeer(secret)
+	req, err := peer.newRequest(ctx, http.MethodGet, peer.keyURL(z.name, "k"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Now().Add(-time.Hour).Unix()
+	req.Header.Set(timestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(signatureHeader, hex.EncodeToString(sign(secret, req.Method, req.URL.RequestURI(), ts, nil)))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expect stale signature to be rejected, got %v", res.Status)
+	}
+
+	// 签名覆盖查询参数
+	req, err = peer.newRequest(ctx, http.MethodGet, peer.keyURL(z.name, "k"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.URL.RawQuery = "tampered=1"
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expect a tampered query to be rejected, got %v", res.Status)
+	}
+}
+
+// zeros 无限输出 0 的 io.Reader
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestAuthenticateBodyLimit(t *testing.T) {
+	pool := NewHTTPPoolOpts("self", &HTTPPoolOptions{Secret: []byte("s3cret")})
+	r := httptest.NewRequest(http.MethodPut, "/zcache/g/k", io.LimitReader(zeros{}, maxRequestBodyBytes+1))
+	r.Header.Set(timestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	r.Header.Set(signatureHeader, "00")
+	if err := pool.authenticate(httptest.NewRecorder(), r); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("expect ErrBadRequest for an oversized body, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package zcache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断器处于打开状态，请求没有发往远程节点，errors.Is(err, ErrPeerUnavailable) 同样成立
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker is open", ErrPeerUnavailable)
+
+const (
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerOpenTimeout      = 5 * time.Second
+	defaultBreakerHalfOpenRequests = 1
+)
+
+// BreakerState 熔断器的状态
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // 正常放行请求
+	BreakerOpen                         // 拒绝所有请求
+	BreakerHalfOpen                     // 放行少量试探请求
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerOptions 每个远程节点的熔断器配置，零值字段使用默认值
+type BreakerOptions struct {
+	// FailureThreshold 连续失败多少次后打开熔断器，默认为 5
+	FailureThreshold int
+
+	// OpenTimeout 熔断器打开多久后进入半开状态，默认为 5 秒
+	OpenTimeout time.Duration
+
+	// HalfOpenRequests 半开状态下允许同时进行的试探请求数，全部成功后关闭熔断器，默认为 1
+	HalfOpenRequests int
+}
+
+// circuitBreaker 三态熔断器：closed -> open -> half-open -> closed
+type circuitBreaker struct {
+	opts BreakerOptions
+	now  func() time.Time
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int       // closed 状态下连续失败的次数
+	openedAt  time.Time // 最近一次打开的时间
+	trials    int       // half-open 状态下正在进行的试探请求数
+	successes int       // half-open 状态下成功的试探请求数
+	trips     int64     // 打开的总次数
+	rejected  int64     // 被拒绝的请求总数
+}
+
+func newCircuitBreaker(opts BreakerOptions) *circuitBreaker {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = defaultBreakerFailureThreshold
+	}
+	if opts.OpenTimeout == 0 {
+		opts.OpenTimeout = defaultBreakerOpenTimeout
+	}
+	if opts.HalfOpenRequests == 0 {
+		opts.HalfOpenRequests = defaultBreakerHalfOpenRequests
+	}
+	return &circuitBreaker{opts: opts, now: time.Now}
+}
+
+// allow 判断是否放行请求，放行后必须调用 record 报告结果
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.opts.OpenTimeout {
+		b.state = BreakerHalfOpen
+		b.trials, b.successes = 0, 0
+	}
+	switch b.state {
+	case BreakerOpen:
+		b.rejected++
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.trials >= b.opts.HalfOpenRequests {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.trials++
+	}
+	return nil
+}
+
+// record 报告一次放行的请求是否失败
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.opts.FailureThreshold {
+			b.trip()
+		}
+	case BreakerHalfOpen:
+		if failed {
+			b.trip()
+			return
+		}
+		b.successes++
+		if b.successes >= b.opts.HalfOpenRequests {
+			b.state = BreakerClosed
+			b.failures = 0
+		}
+	}
+}
+
+// cancel 放行的请求被调用方取消，不计入成功或失败
+func (b *circuitBreaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen && b.trials > 0 {
+		b.trials--
+	}
+}
+
+func (b *circuitBreaker) trip() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.trips++
+}
+
+// snapshot 返回当前状态和统计数据
+func (b *circuitBreaker) snapshot() (state BreakerState, trips, rejected int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state = b.state
+	if state == BreakerOpen && b.now().Sub(b.openedAt) >= b.opts.OpenTimeout {
+		state = BreakerHalfOpen
+	}
+	return state, b.trips, b.rejected
+}
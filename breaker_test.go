@@ -0,0 +1,133 @@
+package zcache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	pb "zcache/zcachepb"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	b := newCircuitBreaker(BreakerOptions{FailureThreshold: 2, OpenTimeout: time.Second})
+	b.now = clock.Now
+
+	for i := 0; i < 2; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("closed breaker should allow requests, got %v", err)
+		}
+		b.record(true)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expect open breaker to reject, got %v", err)
+	}
+
+	clock.Advance(time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatalf("half-open breaker should allow a trial, got %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("half-open breaker should allow only one trial, got %v", err)
+	}
+	b.record(true)
+	if state, trips, _ := b.snapshot(); state != BreakerOpen || trips != 2 {
+		t.Fatalf("failed trial should re-open the breaker, got %v after %d trips", state, trips)
+	}
+
+	clock.Advance(time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatal(err)
+	}
+	b.record(false)
+	state, _, rejected := b.snapshot()
+	if state != BreakerClosed || rejected != 2 {
+		t.Fatalf("successful trial should close the breaker, got %v with %d rejected", state, rejected)
+	}
+}
+
+func TestCircuitBreakerDefaults(t *testing.T) {
+	b := newCircuitBreaker(BreakerOptions{})
+	for i := 0; i < defaultBreakerFailureThreshold; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("zero options should open only after %d failures, rejected after %d", defaultBreakerFailureThreshold, i)
+		}
+		b.record(true)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expect open breaker to reject, got %v", err)
+	}
+}
+
+func TestHTTPPoolBreaker(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{
+		Breaker: &BreakerOptions{FailureThreshold: 2, OpenTimeout: time.Hour},
+	})
+	pool.Set("http://self", srv.URL)
+	key, peer := remoteKey(t, pool)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		err := peer.Get(ctx, &pb.Request{Group: "g", Key: key}, &pb.Response{})
+		if err == nil {
+			t.Fatalf("expect error from an unavailable peer")
+		}
+		if i == 2 && !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expect ErrCircuitOpen after the breaker trips, got %v", err)
+		}
+	}
+	if n := hits.Load(); n != 2 {
+		t.Fatalf("open breaker should not reach the peer, hits=%d", n)
+	}
+	stats := pool.PeerStats()[srv.URL]
+	if stats.Breaker != BreakerOpen || stats.Trips != 1 || stats.Rejected != 1 {
+		t.Fatalf("unexpected peer stats %+v", stats)
+	}
+}
+
+func TestHTTPPoolBreakerStalledBody(t *testing.T) {
+	// 发送完响应头后不再写入响应体
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(protocolHeader, protocolVersion)
+		w.Header().Set("Content-Type", contentTypeProto)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{
+		Timeout:             20 * time.Millisecond,
+		Breaker:             &BreakerOptions{FailureThreshold: 2, OpenTimeout: time.Hour},
+		HealthCheckInterval: time.Hour,
+		FailureThreshold:    2,
+	})
+	defer pool.StopHealthCheck()
+	pool.Set("http://self", srv.URL)
+	key, peer := remoteKey(t, pool)
+
+	for i := 0; i < 2; i++ {
+		err := peer.Get(context.Background(), &pb.Request{Group: "g", Key: key}, &pb.Response{})
+		if !errors.Is(err, ErrPeerUnavailable) || !transient(err) {
+			t.Fatalf("expect a transient ErrPeerUnavailable from a stalled body, got %v", err)
+		}
+	}
+	if stats := pool.PeerStats()[srv.URL]; stats.Breaker != BreakerOpen || stats.Trips != 1 || stats.Healthy {
+		t.Fatalf("stalled bodies should trip the breaker and eject the peer, got %+v", stats)
+	}
+}
@@ -1,8 +1,13 @@
 package zcache
 
+import "time"
+
 // ByteView 只读数据结构，用于表示缓存值
 type ByteView struct {
-	b []byte // 存储真实的缓存值
+	b       []byte    // 存储真实的缓存值
+	version string    // 源数据返回的版本号，可以为空
+	expire  time.Time // 在本节点缓存中的过期时间，零值表示永不过期
+	noStore bool      // 源数据要求不缓存，其他节点也不应缓存
 }
 
 // Len 实现 Value 接口，返回其所占的内存大小
@@ -14,6 +19,11 @@ func (v ByteView) String() string {
 	return string(v.b)
 }
 
+// Version 返回源数据的版本号，例如 ETag
+func (v ByteView) Version() string {
+	return v.version
+}
+
 func (v ByteView) At(i int) byte {
 	return v.b[i]
 }
@@ -2,6 +2,7 @@ package zcache
 
 import (
 	"sync"
+	"time"
 	"zcache/lru"
 )
 
@@ -10,25 +11,105 @@ type cache struct {
 	mutex      sync.Mutex
 	lru        *lru.Cache
 	cacheBytes int64
+	overhead   int64            // 每条记录额外计入的内存开销
+	now        func() time.Time // 判断过期使用的时钟，为 nil 时使用 time.Now
+	removing   bool             // 正在主动删除，不计入淘汰次数
+	nget, nhit int64
+	nevict     int64 // 因容量不足或过期被淘汰的次数
 }
 
-func (c *cache) add(key string, value ByteView) {
+func (c *cache) add(key string, value ByteView, expire time.Time) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	if c.lru == nil {
-		c.lru = lru.New(c.cacheBytes, nil)
+		c.lru = lru.New(c.cacheBytes, func(string, lru.Value) {
+			if !c.removing {
+				c.nevict++
+			}
+		})
+		c.lru.Now = c.now
+		c.lru.EntryOverhead = c.overhead
 	}
-	c.lru.Add(key, value)
+	c.lru.AddWithExpire(key, value, expire)
 }
 
 func (c *cache) get(key string) (value ByteView, ok bool) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	c.nget++
 	if c.lru == nil {
 		return
 	}
 	if v, hit := c.lru.Get(key); hit {
+		c.nhit++
 		return v.(ByteView), true
 	}
 	return
 }
+
+// removeExpired 清理所有已过期的缓存值，返回清理的数量
+func (c *cache) removeExpired() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.lru == nil {
+		return 0
+	}
+	return c.lru.RemoveExpired()
+}
+
+func (c *cache) len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.lru == nil {
+		return 0
+	}
+	return c.lru.Len()
+}
+
+func (c *cache) remove(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.lru == nil {
+		return
+	}
+	c.removing = true
+	c.lru.Remove(key)
+	c.removing = false
+}
+
+// peek 查找缓存值，不影响淘汰顺序和统计数据
+func (c *cache) peek(key string) (value ByteView, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.lru == nil {
+		return
+	}
+	if v, hit := c.lru.Peek(key); hit {
+		return v.(ByteView), true
+	}
+	return
+}
+
+// clear 清空缓存，不计入淘汰次数
+func (c *cache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.lru == nil {
+		return
+	}
+	c.removing = true
+	c.lru.Clear()
+	c.removing = false
+}
+
+// stats 返回缓存的统计数据
+func (c *cache) stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	s := CacheStats{Gets: c.nget, Hits: c.nhit, Evictions: c.nevict}
+	if c.lru != nil {
+		s.Bytes = c.lru.Bytes()
+		s.Items = int64(c.lru.Len())
+	}
+	return s
+}
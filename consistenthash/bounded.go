@@ -0,0 +1,55 @@
+package consistenthash
+
+import "math"
+
+// 有界负载的一致性哈希（Mirrokni et al., Consistent Hashing with Bounded Loads）
+// 每个实节点的负载上限为 ceil((1+epsilon) * 平均负载)，按虚拟节点数加权；
+// 沿哈希环查找时跳过已经达到上限的节点，因此只有热点 key 会溢出到相邻节点
+
+// SetLoadFactor 设置有界负载系数 epsilon，为 0 时关闭有界负载模式
+func (m *Map) SetLoadFactor(epsilon float64) {
+	m.epsilon = epsilon
+}
+
+// Inc 记录实节点新增一个请求
+func (m *Map) Inc(node string) {
+	if _, ok := m.vnodes[node]; !ok {
+		return
+	}
+	m.loads[node]++
+	m.total++
+}
+
+// Done 记录实节点完成一个请求
+func (m *Map) Done(node string) {
+	if m.loads[node] <= 0 {
+		return
+	}
+	m.loads[node]--
+	m.total--
+}
+
+// Load 返回实节点当前的负载
+func (m *Map) Load(node string) int64 {
+	return m.loads[node]
+}
+
+// MaxLoad 返回实节点在新增一个请求后允许的最大负载
+func (m *Map) MaxLoad(node string) int64 {
+	if len(m.keys) == 0 {
+		return 0
+	}
+	share := float64(m.vnodes[node]) / float64(len(m.keys))
+	return int64(math.Ceil((1 + m.epsilon) * float64(m.total+1) * share))
+}
+
+// getBounded 从虚拟节点 idx 开始顺时针查找第一个未超出负载上限的实节点
+func (m *Map) getBounded(idx int) string {
+	for i := range len(m.keys) {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if m.loads[node]+1 <= m.MaxLoad(node) {
+			return node
+		}
+	}
+	return m.hashMap[m.keys[idx]]
+}
@@ -0,0 +1,75 @@
+package consistenthash
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// simulate 模拟 window 个并发请求，key 的访问频率服从 Zipf 分布
+// 返回整个过程中单个节点负载与平均负载之比的最大值
+func simulate(m *Map, nodes int) float64 {
+	const requests, window = 50000, 200
+	rnd := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(rnd, 1.2, 1, 10000)
+	var inflight []string
+	maxRatio := 0.0
+	for i := 0; i < requests; i++ {
+		node := m.Get("key" + strconv.FormatUint(zipf.Uint64(), 10))
+		m.Inc(node)
+		inflight = append(inflight, node)
+		if len(inflight) > window {
+			m.Done(inflight[0])
+			inflight = inflight[1:]
+		}
+		avg := float64(m.total) / float64(nodes)
+		if ratio := float64(m.Load(node)) / avg; ratio > maxRatio && m.total >= window {
+			maxRatio = ratio
+		}
+	}
+	return maxRatio
+}
+
+func TestBoundedLoadSimulation(t *testing.T) {
+	const nodes, epsilon = 8, 0.25
+	newMap := func() *Map {
+		m := New(50, nil)
+		for i := 0; i < nodes; i++ {
+			m.Add("http://10.0.0." + strconv.Itoa(i) + ":8008")
+		}
+		return m
+	}
+
+	plain := simulate(newMap(), nodes)
+	bounded := newMap()
+	bounded.SetLoadFactor(epsilon)
+	got := simulate(bounded, nodes)
+	t.Logf("max load / average: plain %.2f, bounded %.2f", plain, got)
+
+	// ceil 带来的误差最多为一个请求
+	if limit := 1 + epsilon + float64(nodes)/200; got > limit {
+		t.Fatalf("bounded max load ratio %.2f exceeds %.2f", got, limit)
+	}
+	if plain <= got {
+		t.Fatalf("hot keys should overload a node without bounded loads, plain %.2f", plain)
+	}
+}
+
+func TestBoundedLoadRemove(t *testing.T) {
+	m := New(10, nil)
+	m.SetLoadFactor(0.5)
+	m.Add("a", "b")
+	m.Inc("a")
+	m.Inc("b")
+	m.Inc("b")
+	m.Remove("b")
+	if m.total != 1 || m.Load("b") != 0 {
+		t.Fatalf("Remove should drop the node's load, total=%d", m.total)
+	}
+	m.Done("b")
+	m.Done("a")
+	m.Done("a")
+	if m.total != 0 || m.Load("a") != 0 {
+		t.Fatalf("load must not become negative, total=%d", m.total)
+	}
+}
@@ -2,6 +2,7 @@ package consistenthash
 
 import (
 	"hash/crc32"
+	"slices"
 	"sort"
 	"strconv"
 )
@@ -14,6 +15,11 @@ type Map struct {
 	replicas int               // 实节点对应虚拟节点的倍数
 	keys     []uint32          // 所有虚拟节点的键值
 	hashMap  map[uint32]string // 虚拟节点对实节点的映射
+	vnodes   map[string]int    // 实节点拥有的虚拟节点数
+
+	epsilon float64          // 有界负载系数，为 0 时关闭有界负载模式
+	loads   map[string]int64 // 实节点当前的负载
+	total   int64            // 所有实节点负载之和
 }
 
 // New 创建一个一致性哈希实例
@@ -24,6 +30,8 @@ func New(replicas int, fn Hash) *Map {
 		hash:     fn,
 		replicas: replicas,
 		hashMap:  make(map[uint32]string),
+		vnodes:   make(map[string]int),
+		loads:    make(map[string]int64),
 	}
 	if m.hash == nil {
 		m.hash = crc32.ChecksumIEEE
@@ -31,20 +39,79 @@ func New(replicas int, fn Hash) *Map {
 	return m
 }
 
-// Add 添加实节点
+// Add 添加实节点，每个节点的权重都为 1，已存在的节点会被忽略
 func (m *Map) Add(keys ...string) {
 	for _, key := range keys {
-		for i := range m.replicas {
-			hash := m.hash([]byte(strconv.Itoa(i) + key))
-			m.keys = append(m.keys, hash)
-			m.hashMap[hash] = key
+		if _, ok := m.vnodes[key]; !ok {
+			m.addVirtual(key, m.replicas)
+		}
+	}
+	m.sortKeys()
+}
+
+// AddWeighted 按权重添加实节点，虚拟节点数为 replicas * weight，
+// 节点分到的 key 的比例与权重成正比，已存在的节点会被忽略
+func (m *Map) AddWeighted(weights map[string]int) {
+	for key, weight := range weights {
+		if _, ok := m.vnodes[key]; !ok {
+			m.addVirtual(key, m.replicas*weight)
 		}
 	}
+	m.sortKeys()
+}
+
+// addVirtual 为实节点添加 n 个虚拟节点
+func (m *Map) addVirtual(key string, n int) {
+	m.placeVirtual(key, n)
+	m.vnodes[key] = n
+}
+
+// placeVirtual 将实节点的 n 个虚拟节点放到环上
+// 多个节点的虚拟节点哈希冲突时，该位置属于名称最小的节点，与添加顺序无关
+func (m *Map) placeVirtual(key string, n int) {
+	for i := range n {
+		hash := m.hash([]byte(strconv.Itoa(i) + key))
+		if node, ok := m.hashMap[hash]; ok {
+			if key < node {
+				m.hashMap[hash] = key
+			}
+			continue
+		}
+		m.keys = append(m.keys, hash)
+		m.hashMap[hash] = key
+	}
+}
+
+func (m *Map) sortKeys() {
 	sort.Slice(m.keys, func(i, j int) bool {
 		return m.keys[i] < m.keys[j]
 	})
 }
 
+// Remove 删除实节点及其所有虚拟节点，其余节点的位置保持不变
+// 与被删除节点哈希冲突的虚拟节点重新归属于剩余的节点
+func (m *Map) Remove(keys ...string) {
+	removed := false
+	for _, key := range keys {
+		if _, ok := m.vnodes[key]; !ok {
+			continue
+		}
+		removed = true
+		delete(m.vnodes, key)
+		m.total -= m.loads[key]
+		delete(m.loads, key)
+	}
+	if !removed {
+		return
+	}
+	m.keys = m.keys[:0]
+	clear(m.hashMap)
+	for key, n := range m.vnodes {
+		m.placeVirtual(key, n)
+	}
+	m.sortKeys()
+}
+
 // Get 根据 key 获取最接近的实节点
 func (m *Map) Get(key string) string {
 	if len(m.keys) == 0 {
@@ -55,5 +122,29 @@ func (m *Map) Get(key string) string {
 		return m.keys[i] >= hash
 	})
 	idx = idx % len(m.keys)
+	if m.epsilon > 0 {
+		return m.getBounded(idx)
+	}
 	return m.hashMap[m.keys[idx]]
 }
+
+// GetN 从 key 的位置开始顺时针查找 n 个不同的实节点，用于多副本
+// 第一个节点与关闭有界负载时 Get 的结果相同，节点数不足 n 时返回全部节点
+func (m *Map) GetN(key string, n int) []string {
+	if len(m.keys) == 0 || n <= 0 {
+		return nil
+	}
+	n = min(n, len(m.vnodes))
+	hash := m.hash([]byte(key))
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+	nodes := make([]string, 0, n)
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if !slices.Contains(nodes, node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
@@ -1,6 +1,7 @@
 package consistenthash
 
 import (
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -36,3 +37,173 @@ func TestHash(t *testing.T) {
 		}
 	}
 }
+
+func TestRemove(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	hash.Add("6", "4", "2", "8")
+	hash.Remove("8")
+
+	testCases := map[string]string{
+		"2":  "2",
+		"11": "2",
+		"23": "4",
+		"27": "2",
+	}
+	for k, v := range testCases {
+		if hash.Get(k) != v {
+			t.Errorf("Asking for %s, should have yielded %s", k, v)
+		}
+	}
+
+	hash.Remove("6", "4", "2")
+	if len(hash.keys) != 0 || len(hash.hashMap) != 0 || hash.Get("2") != "" {
+		t.Fatalf("ring should be empty, keys=%v", hash.keys)
+	}
+}
+
+func TestRemoveCollision(t *testing.T) {
+	// 所有虚拟节点的哈希都相同
+	hash := New(3, func([]byte) uint32 { return 7 })
+	hash.Add("b", "a")
+	if got := hash.Get("k"); got != "a" {
+		t.Fatalf("colliding point should belong to the smallest node, got %q", got)
+	}
+	hash.Remove("a")
+	if got := hash.Get("k"); got != "b" {
+		t.Fatalf("colliding point should move to the remaining node, got %q", got)
+	}
+	hash.Add("a")
+	hash.Remove("b")
+	if got, nodes := hash.Get("k"), hash.GetN("k", 2); got != "a" || len(nodes) != 1 || nodes[0] != "a" {
+		t.Fatalf("expect only a to remain, got %q and %v", got, nodes)
+	}
+	hash.Remove("a")
+	if len(hash.keys) != 0 || len(hash.hashMap) != 0 {
+		t.Fatalf("ring should be empty, keys=%v hashMap=%v", hash.keys, hash.hashMap)
+	}
+}
+
+func TestAddExisting(t *testing.T) {
+	hash := New(3, nil)
+	hash.Add("a", "b")
+	hash.Add("a")
+	hash.AddWeighted(map[string]int{"b": 5})
+	if len(hash.keys) != 6 || hash.vnodes["a"] != 3 || hash.vnodes["b"] != 3 {
+		t.Fatalf("adding existing nodes should be ignored, keys=%v vnodes=%v", hash.keys, hash.vnodes)
+	}
+}
+
+// 增删一个节点时，只有约 1/N 的 key 需要迁移，且只迁移到新节点或从被删节点迁出
+func TestRemapOnChange(t *testing.T) {
+	const n, samples = 10, 100000
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = "http://10.0.0." + strconv.Itoa(i) + ":8008"
+	}
+	hash := New(50, nil)
+	hash.Add(nodes...)
+	before := make([]string, samples)
+	for i := range before {
+		before[i] = hash.Get("key" + strconv.Itoa(i))
+	}
+
+	added := "http://10.0.0.100:8008"
+	hash.Add(added)
+	moved := 0
+	for i, owner := range before {
+		if now := hash.Get("key" + strconv.Itoa(i)); now != owner {
+			if now != added {
+				t.Fatalf("key%d moved from %s to %s, not to the new node", i, owner, now)
+			}
+			moved++
+		}
+	}
+	if share := float64(moved) / samples; share > 2.0/(n+1) {
+		t.Fatalf("adding a node moved %.2f%% keys, expect about %.2f%%", share*100, 100.0/(n+1))
+	}
+
+	hash.Remove(added)
+	for i, owner := range before {
+		if now := hash.Get("key" + strconv.Itoa(i)); now != owner {
+			t.Fatalf("key%d should return to %s after removal, got %s", i, owner, now)
+		}
+	}
+
+	removed := nodes[0]
+	hash.Remove(removed)
+	moved = 0
+	for i, owner := range before {
+		if now := hash.Get("key" + strconv.Itoa(i)); now != owner {
+			if owner != removed {
+				t.Fatalf("key%d moved from %s although it was not removed", i, owner)
+			}
+			moved++
+		}
+	}
+	if share := float64(moved) / samples; share > 2.0/n {
+		t.Fatalf("removing a node moved %.2f%% keys, expect about %.2f%%", share*100, 100.0/n)
+	}
+}
+
+// 每个节点分到的 key 的比例与权重成正比
+func TestWeightedDistribution(t *testing.T) {
+	const samples = 100000
+	weights := map[string]int{
+		"http://10.0.0.1:8008": 1,
+		"http://10.0.0.2:8008": 2,
+		"http://10.0.0.3:8008": 3,
+		"http://10.0.0.4:8008": 4,
+	}
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	hash := New(100, nil)
+	hash.AddWeighted(weights)
+
+	counts := make(map[string]int)
+	for i := 0; i < samples; i++ {
+		counts[hash.Get("key"+strconv.Itoa(i))]++
+	}
+	for node, w := range weights {
+		expect := float64(w) / float64(total)
+		got := float64(counts[node]) / samples
+		if got < expect*0.75 || got > expect*1.25 {
+			t.Errorf("%s with weight %d got %.2f%% keys, expect about %.2f%%", node, w, got*100, expect*100)
+		}
+	}
+
+	hash.Remove("http://10.0.0.4:8008")
+	if len(hash.keys) != 100*(1+2+3) {
+		t.Fatalf("Remove should drop every virtual node, %d left", len(hash.keys))
+	}
+}
+
+func TestGetN(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	hash.Add("6", "4", "2")
+
+	testCases := map[string][]string{
+		"2":  {"2", "4", "6"},
+		"11": {"2", "4", "6"},
+		"23": {"4", "6", "2"},
+		"27": {"2", "4", "6"},
+	}
+	for k, v := range testCases {
+		if got := hash.GetN(k, 3); !reflect.DeepEqual(got, v) {
+			t.Errorf("Asking for %s, should have yielded %v, got %v", k, v, got)
+		}
+		if got := hash.GetN(k, 1); got[0] != hash.Get(k) {
+			t.Errorf("GetN(%s, 1) = %v, Get = %s", k, got, hash.Get(k))
+		}
+	}
+	if got := hash.GetN("23", 10); len(got) != 3 {
+		t.Fatalf("expect all 3 nodes, got %v", got)
+	}
+}
@@ -0,0 +1,60 @@
+package consistenthash
+
+import (
+	"hash/crc32"
+	"slices"
+)
+
+// Jump 跳跃一致性哈希（Lamping & Veach），不需要额外内存且分布均匀
+// 节点按名称排序后编号，相同的节点集合无论添加顺序如何都得到相同的分配；
+// 只有增删排在末尾的节点时迁移的 key 最少，增删中间的节点会导致大量 key 迁移
+type Jump struct {
+	hash  Hash
+	nodes []string
+}
+
+// NewJump 创建一个跳跃一致性哈希实例，fn 为 nil 时使用 crc32.ChecksumIEEE
+func NewJump(fn Hash) *Jump {
+	if fn == nil {
+		fn = crc32.ChecksumIEEE
+	}
+	return &Jump{hash: fn}
+}
+
+// Add 添加实节点，已存在的节点会被忽略
+func (j *Jump) Add(nodes ...string) {
+	for _, node := range nodes {
+		if indexOf(j.nodes, node) < 0 {
+			j.nodes = append(j.nodes, node)
+		}
+	}
+	slices.Sort(j.nodes)
+}
+
+// Remove 删除实节点，后面的节点依次前移
+func (j *Jump) Remove(nodes ...string) {
+	for _, node := range nodes {
+		if i := indexOf(j.nodes, node); i >= 0 {
+			j.nodes = append(j.nodes[:i], j.nodes[i+1:]...)
+		}
+	}
+}
+
+// Get 返回 key 所属的实节点
+func (j *Jump) Get(key string) string {
+	if len(j.nodes) == 0 {
+		return ""
+	}
+	return j.nodes[jumpHash(mix64(uint64(j.hash([]byte(key)))), len(j.nodes))]
+}
+
+// jumpHash 将 key 映射到 [0, buckets) 中的一个桶
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,123 @@
+package consistenthash
+
+import (
+	"hash/crc32"
+	"slices"
+)
+
+// DefaultMaglevSize Maglev 查找表的默认大小，需要是远大于节点数的质数
+const DefaultMaglevSize = 65537
+
+// Maglev Google Maglev 负载均衡器使用的一致性哈希
+// 预先计算一张查找表，查找只需要一次取模，分布几乎完全均匀；增删节点时需要重建查找表，
+// 并且会有少量额外的 key 迁移
+type Maglev struct {
+	hash  Hash
+	size  uint64
+	nodes []string
+	table []int // 查找表，保存 nodes 的下标
+}
+
+// NewMaglev 创建一个 Maglev 实例
+// size 查找表的大小，不是质数时向上取整到下一个质数，小于等于 0 时使用 DefaultMaglevSize
+// fn 哈希函数，默认为 crc32.ChecksumIEEE
+func NewMaglev(size int, fn Hash) *Maglev {
+	if size <= 0 {
+		size = DefaultMaglevSize
+	}
+	// 只有 size 是质数时，每个节点的排列才能遍历查找表的所有位置，否则 populate 可能无法结束
+	size = nextPrime(size)
+	if fn == nil {
+		fn = crc32.ChecksumIEEE
+	}
+	return &Maglev{hash: fn, size: uint64(size)}
+}
+
+// Add 添加实节点，已存在的节点会被忽略
+// 节点按名称排序，相同的节点集合无论添加顺序如何都得到相同的查找表
+func (m *Maglev) Add(nodes ...string) {
+	for _, node := range nodes {
+		if indexOf(m.nodes, node) < 0 {
+			m.nodes = append(m.nodes, node)
+		}
+	}
+	slices.Sort(m.nodes)
+	m.populate()
+}
+
+// Remove 删除实节点
+func (m *Maglev) Remove(nodes ...string) {
+	for _, node := range nodes {
+		if i := indexOf(m.nodes, node); i >= 0 {
+			m.nodes = append(m.nodes[:i], m.nodes[i+1:]...)
+		}
+	}
+	m.populate()
+}
+
+// Get 返回 key 所属的实节点
+func (m *Maglev) Get(key string) string {
+	if len(m.nodes) == 0 {
+		return ""
+	}
+	h := mix64(uint64(m.hash([]byte(key))))
+	return m.nodes[m.table[h%m.size]]
+}
+
+// populate 按每个节点的排列轮流填充查找表
+func (m *Maglev) populate() {
+	n := len(m.nodes)
+	if n == 0 {
+		m.table = nil
+		return
+	}
+	offsets := make([]uint64, n)
+	skips := make([]uint64, n)
+	for i, node := range m.nodes {
+		h := mix64(uint64(m.hash([]byte(node))))
+		offsets[i] = (h >> 32) % m.size
+		skips[i] = (h&0xffffffff)%(m.size-1) + 1
+	}
+
+	table := make([]int, m.size)
+	for i := range table {
+		table[i] = -1
+	}
+	next := make([]uint64, n)
+	filled := uint64(0)
+	for {
+		for i := 0; i < n; i++ {
+			c := (offsets[i] + next[i]*skips[i]) % m.size
+			for table[c] >= 0 {
+				next[i]++
+				c = (offsets[i] + next[i]*skips[i]) % m.size
+			}
+			table[c] = i
+			next[i]++
+			filled++
+			if filled == m.size {
+				m.table = table
+				return
+			}
+		}
+	}
+}
+
+// nextPrime 返回大于等于 n 的最小质数
+func nextPrime(n int) int {
+	if n <= 2 {
+		return 2
+	}
+	for ; ; n++ {
+		prime := true
+		for d := 2; d*d <= n; d++ {
+			if n%d == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			return n
+		}
+	}
+}
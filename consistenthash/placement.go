@@ -0,0 +1,62 @@
+package consistenthash
+
+// Placement 决定 key 由哪个实节点负责，HTTPPool 通过它选择节点
+// 实现不需要并发安全，由调用方加锁
+type Placement interface {
+	// Add 添加实节点
+	Add(nodes ...string)
+	// Remove 删除实节点
+	Remove(nodes ...string)
+	// Get 返回 key 所属的实节点，没有节点时返回空字符串
+	Get(key string) string
+}
+
+// WeightedPlacement 可选接口，支持按权重分配 key
+type WeightedPlacement interface {
+	Placement
+	AddWeighted(weights map[string]int)
+}
+
+// BoundedPlacement 可选接口，支持有界负载
+type BoundedPlacement interface {
+	Placement
+	SetLoadFactor(epsilon float64)
+	Inc(node string)
+	Done(node string)
+}
+
+// ReplicatedPlacement 可选接口，支持为每个 key 选出多个不同的副本节点
+type ReplicatedPlacement interface {
+	Placement
+	GetN(key string, n int) []string
+}
+
+// 静态类型检查
+var (
+	_ WeightedPlacement   = (*Map)(nil)
+	_ BoundedPlacement    = (*Map)(nil)
+	_ ReplicatedPlacement = (*Map)(nil)
+	_ ReplicatedPlacement = (*Rendezvous)(nil)
+	_ Placement           = (*Jump)(nil)
+	_ Placement           = (*Maglev)(nil)
+)
+
+// mix64 splitmix64 的终结函数，将哈希值打散到 64 位
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// indexOf 返回 node 在 nodes 中的下标，不存在时返回 -1
+func indexOf(nodes []string, node string) int {
+	for i, n := range nodes {
+		if n == node {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,171 @@
+package consistenthash
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+var placements = []struct {
+	name string
+	new  func() Placement
+	// tolerance 单个节点分到的 key 相对平均值允许的偏差
+	tolerance float64
+}{
+	{"ring", func() Placement { return New(50, nil) }, 0.35},
+	{"rendezvous", func() Placement { return NewRendezvous(nil) }, 0.1},
+	{"jump", func() Placement { return NewJump(nil) }, 0.1},
+	{"maglev", func() Placement { return NewMaglev(0, nil) }, 0.1},
+}
+
+// testNodes 返回 n 个按名称排序的节点，Jump 按名称排序编号，最后一个节点排在末尾
+func testNodes(n int) []string {
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = "http://10.0.0.1:" + strconv.Itoa(8000+i)
+	}
+	return nodes
+}
+
+func TestPlacementBalance(t *testing.T) {
+	const n, samples = 10, 100000
+	for _, pc := range placements {
+		t.Run(pc.name, func(t *testing.T) {
+			p := pc.new()
+			p.Add(testNodes(n)...)
+			counts := make(map[string]int)
+			for i := 0; i < samples; i++ {
+				counts[p.Get("key"+strconv.Itoa(i))]++
+			}
+			if len(counts) != n {
+				t.Fatalf("expect keys on %d nodes, got %d", n, len(counts))
+			}
+			avg := float64(samples) / n
+			for node, c := range counts {
+				if dev := float64(c)/avg - 1; dev > pc.tolerance || dev < -pc.tolerance {
+					t.Errorf("%s got %d keys, %.1f%% away from average", node, c, dev*100)
+				}
+			}
+		})
+	}
+}
+
+func TestPlacementDisruption(t *testing.T) {
+	const n, samples = 10, 100000
+	for _, pc := range placements {
+		t.Run(pc.name, func(t *testing.T) {
+			p := pc.new()
+			nodes := testNodes(n + 1)
+			p.Add(nodes[:n]...)
+			before := make([]string, samples)
+			for i := range before {
+				before[i] = p.Get("key" + strconv.Itoa(i))
+			}
+
+			// 在末尾添加一个节点，理想情况下迁移 1/(n+1) 的 key，且都迁移到新节点
+			p.Add(nodes[n])
+			moved, misplaced := 0, 0
+			for i, owner := range before {
+				if now := p.Get("key" + strconv.Itoa(i)); now != owner {
+					moved++
+					if now != nodes[n] {
+						misplaced++
+					}
+				}
+			}
+			share := float64(moved) / samples
+			t.Logf("adding a node moved %.2f%% keys (ideal %.2f%%), %d to old nodes", share*100, 100.0/(n+1), misplaced)
+			if share > 1.5/(n+1) {
+				t.Errorf("adding a node moved %.2f%% keys", share*100)
+			}
+			if float64(misplaced) > 0.01*samples {
+				t.Errorf("%d keys moved between old nodes", misplaced)
+			}
+
+			// 删除刚添加的节点后 key 回到原来的节点
+			p.Remove(nodes[n])
+			back := 0
+			for i, owner := range before {
+				if p.Get("key"+strconv.Itoa(i)) == owner {
+					back++
+				}
+			}
+			if float64(back) < 0.99*samples {
+				t.Errorf("only %d of %d keys returned after removal", back, samples)
+			}
+		})
+	}
+}
+
+func TestPlacementOrderIndependent(t *testing.T) {
+	nodes := testNodes(10)
+	reversed := slices.Clone(nodes)
+	slices.Reverse(reversed)
+	for _, pc := range placements {
+		t.Run(pc.name, func(t *testing.T) {
+			a, b := pc.new(), pc.new()
+			a.Add(nodes...)
+			for _, node := range reversed {
+				b.Add(node)
+			}
+			// 删除后重新加入的节点应回到原来的位置
+			b.Remove(nodes[3])
+			b.Add(nodes[3])
+			for i := 0; i < 10000; i++ {
+				key := "key" + strconv.Itoa(i)
+				if a.Get(key) != b.Get(key) {
+					t.Fatalf("%s owned by %s and %s depending on add order", key, a.Get(key), b.Get(key))
+				}
+			}
+		})
+	}
+}
+
+func TestMaglevPrimeSize(t *testing.T) {
+	m := NewMaglev(100, nil)
+	if m.size != 101 {
+		t.Fatalf("expect size rounded up to 101, got %d", m.size)
+	}
+	m.Add(testNodes(7)...)
+	counts := make(map[int]int)
+	for _, i := range m.table {
+		counts[i]++
+	}
+	if len(counts) != 7 {
+		t.Fatalf("expect every node in the lookup table, got %v", counts)
+	}
+}
+
+func BenchmarkPlacementGet(b *testing.B) {
+	for _, n := range []int{8, 64} {
+		for _, pc := range placements {
+			b.Run(pc.name+"/"+strconv.Itoa(n), func(b *testing.B) {
+				p := pc.new()
+				p.Add(testNodes(n)...)
+				keys := make([]string, 1024)
+				for i := range keys {
+					keys[i] = "key" + strconv.Itoa(i)
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					p.Get(keys[i&1023])
+				}
+			})
+		}
+	}
+}
+
+func TestRendezvousGetN(t *testing.T) {
+	r := NewRendezvous(nil)
+	r.Add(testNodes(5)...)
+	for i := 0; i < 100; i++ {
+		key := "key" + strconv.Itoa(i)
+		nodes := r.GetN(key, 3)
+		if len(nodes) != 3 || nodes[0] != r.Get(key) {
+			t.Fatalf("GetN(%s, 3) = %v, Get = %s", key, nodes, r.Get(key))
+		}
+		if nodes[0] == nodes[1] || nodes[1] == nodes[2] || nodes[0] == nodes[2] {
+			t.Fatalf("GetN returned duplicate nodes %v", nodes)
+		}
+	}
+}
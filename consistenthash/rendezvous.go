@@ -0,0 +1,80 @@
+package consistenthash
+
+import (
+	"hash/crc32"
+	"sort"
+)
+
+// Rendezvous 最高随机权重哈希（HRW），key 属于与它组合后得分最高的节点
+// 分布均匀且增删节点时只迁移必要的 key，但每次查找需要遍历所有节点
+type Rendezvous struct {
+	hash   Hash
+	nodes  []string
+	hashes []uint64 // 每个节点的哈希值，与 nodes 一一对应
+}
+
+// NewRendezvous 创建一个 HRW 实例，fn 为 nil 时使用 crc32.ChecksumIEEE
+func NewRendezvous(fn Hash) *Rendezvous {
+	if fn == nil {
+		fn = crc32.ChecksumIEEE
+	}
+	return &Rendezvous{hash: fn}
+}
+
+// Add 添加实节点，已存在的节点会被忽略
+func (r *Rendezvous) Add(nodes ...string) {
+	for _, node := range nodes {
+		if indexOf(r.nodes, node) >= 0 {
+			continue
+		}
+		r.nodes = append(r.nodes, node)
+		r.hashes = append(r.hashes, mix64(uint64(r.hash([]byte(node)))))
+	}
+}
+
+// Remove 删除实节点
+func (r *Rendezvous) Remove(nodes ...string) {
+	for _, node := range nodes {
+		if i := indexOf(r.nodes, node); i >= 0 {
+			r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+			r.hashes = append(r.hashes[:i], r.hashes[i+1:]...)
+		}
+	}
+}
+
+// Get 返回得分最高的实节点
+func (r *Rendezvous) Get(key string) string {
+	if len(r.nodes) == 0 {
+		return ""
+	}
+	kh := uint64(r.hash([]byte(key)))
+	best, bestScore := 0, uint64(0)
+	for i, nh := range r.hashes {
+		if score := mix64(nh ^ kh); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return r.nodes[best]
+}
+
+// GetN 返回得分最高的 n 个实节点，第一个与 Get 的结果相同
+func (r *Rendezvous) GetN(key string, n int) []string {
+	if len(r.nodes) == 0 || n <= 0 {
+		return nil
+	}
+	kh := uint64(r.hash([]byte(key)))
+	idx := make([]int, len(r.nodes))
+	scores := make([]uint64, len(r.nodes))
+	for i, nh := range r.hashes {
+		idx[i] = i
+		scores[i] = mix64(nh ^ kh)
+	}
+	sort.Slice(idx, func(a, b int) bool {
+		return scores[idx[a]] > scores[idx[b]]
+	})
+	nodes := make([]string, 0, min(n, len(idx)))
+	for _, i := range idx[:cap(nodes)] {
+		nodes = append(nodes, r.nodes[i])
+	}
+	return nodes
+}
@@ -0,0 +1,37 @@
+package zcache
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// 可以用 errors.Is 判断的错误类型
+var (
+	// ErrNoSuchGroup 请求的 Group 不存在
+	ErrNoSuchGroup = errors.New("zcache: no such group")
+
+	// ErrPeerUnavailable 远程节点暂时不可用：网络错误、超时、熔断或返回了 502/503/504
+	ErrPeerUnavailable = errors.New("zcache: peer unavailable")
+
+	// ErrBadRequest 请求不合法，例如 key 为空、路径或请求体格式错误
+	ErrBadRequest = errors.New("zcache: bad request")
+)
+
+// errEmptyKey key 为空时返回的错误
+var errEmptyKey = fmt.Errorf("%w: key 字段为空", ErrBadRequest)
+
+// httpStatus 返回错误对应的 HTTP 状态码
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNoSuchGroup):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrPeerUnavailable):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
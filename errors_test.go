@@ -0,0 +1,139 @@
+package zcache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	pb "zcache/zcachepb"
+)
+
+func TestHTTPErrors(t *testing.T) {
+	z := NewGroup("http-errors", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+
+	// 不匹配的路径前缀返回 400，不会 panic
+	res, err := http.Get(srv.URL + "/other/path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expect 400 for an invalid prefix, got %v", res.Status)
+	}
+
+	peer := &httpGetter{baseURL: srv.URL + defaultBasePath}
+	ctx := context.Background()
+	for _, tt := range []struct {
+		group, key string
+		target     error
+	}{
+		{"no-such-group", "k", ErrNoSuchGroup},
+		{z.name, "", ErrBadRequest},
+	} {
+		err := peer.Get(ctx, &pb.Request{Group: tt.group, Key: tt.key}, &pb.Response{})
+		if !errors.Is(err, tt.target) {
+			t.Fatalf("Get(%q, %q): expect %v, got %v", tt.group, tt.key, tt.target, err)
+		}
+	}
+}
+
+func TestPeerUnavailable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	dead := srv.URL
+	srv.Close()
+
+	pool := NewHTTPPool("http://self")
+	pool.Set("http://self", dead)
+	key, peer := remoteKey(t, pool)
+	err := peer.Get(context.Background(), &pb.Request{Group: "g", Key: key}, &pb.Response{})
+	if !errors.Is(err, ErrPeerUnavailable) {
+		t.Fatalf("expect ErrPeerUnavailable, got %v", err)
+	}
+	if !errors.Is(ErrCircuitOpen, ErrPeerUnavailable) {
+		t.Fatalf("ErrCircuitOpen should be an ErrPeerUnavailable")
+	}
+}
+
+func TestRecover(t *testing.T) {
+	srv := httptest.NewServer(Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+	defer srv.Close()
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expect 500, got %v", res.Status)
+	}
+}
+
+func TestHTTPPoolRecover(t *testing.T) {
+	calls := 0
+	z := NewGroup("http-panic", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			calls++
+			if calls == 1 {
+				panic("getter bug")
+			}
+			return []byte("ok"), nil
+		}))
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+
+	peer := &httpGetter{baseURL: srv.URL + defaultBasePath}
+	err := peer.Get(context.Background(), &pb.Request{Group: z.name, Key: "k"}, &pb.Response{})
+	var se *statusError
+	if !errors.As(err, &se) || se.code != http.StatusInternalServerError {
+		t.Fatalf("expect 500 from a panicking getter, got %v", err)
+	}
+	// 同一个 key 之后仍然可以正常加载
+	out := &pb.Response{}
+	if err := peer.Get(context.Background(), &pb.Request{Group: z.name, Key: "k"}, out); err != nil || string(out.GetValue()) != "ok" {
+		t.Fatalf("expect recovery after panic, got %q, %v", out.GetValue(), err)
+	}
+}
+
+// closeErrBody Close 时返回错误的响应体
+type closeErrBody struct {
+	io.Reader
+}
+
+func (closeErrBody) Close() error {
+	return errors.New("close failed")
+}
+
+// closeErrTransport 返回响应体无法关闭的响应
+type closeErrTransport struct{}
+
+func (closeErrTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res := &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{},
+		Body:       closeErrBody{strings.NewReader("")},
+		Request:    req,
+	}
+	res.Header.Set(protocolHeader, protocolVersion)
+	res.Header.Set("Content-Type", contentTypeProto)
+	return res, nil
+}
+
+func TestHTTPGetterCloseError(t *testing.T) {
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{Transport: closeErrTransport{}})
+	pool.Set("http://self", "http://peer")
+	key, peer := remoteKey(t, pool)
+	err := peer.Get(context.Background(), &pb.Request{Group: "g", Key: key}, &pb.Response{})
+	if err == nil || !strings.Contains(err.Error(), "close failed") {
+		t.Fatalf("expect the close error to be returned, got %v", err)
+	}
+}
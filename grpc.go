@@ -0,0 +1,304 @@
+package zcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"log/slog"
+	"net/http"
+	"strings"
+	"zcache/consistenthash"
+	pb "zcache/zcachepb"
+)
+
+// GRPCPool 实现 gRPC 协议的分布式缓存服务，可以替代 HTTPPool 作为 PeerPicker 使用
+// 支持权重、增量增删节点、多副本和自定义节点选择策略；健康检查、熔断器和有界负载只有 HTTPPool 支持
+type GRPCPool struct {
+	peerSet[*grpcGetter] // 节点成员，keyed by e.g. "10.0.0.2:8008"
+
+	self        string                   // 记录地址，例如 "10.0.0.2:8008"
+	dialOptions []grpc.DialOption        // 连接其他节点时使用的选项
+	logger      *slog.Logger             // 带有 server 属性的日志
+	logRequests bool                     // 是否以 Debug 级别记录每个请求
+	tracer      Tracer                   // 通过 metadata 在节点之间传递追踪上下文
+	lookup      func(name string) *Group // 按名称查找 Group，默认为 GetGroup
+}
+
+// NewGRPCPool 创建一个新的 GRPCPool，未指定 opts 时使用不加密的连接
+func NewGRPCPool(self string, opts ...grpc.DialOption) *GRPCPool {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	p := &GRPCPool{
+		self:        self,
+		dialOptions: opts,
+		logger:      slog.Default().With("server", self),
+		lookup:      GetGroup,
+		tracer:      NoopTracer{},
+	}
+	p.peerSet = peerSet[*grpcGetter]{
+		self: self,
+		newPlacement: func() consistenthash.Placement {
+			return consistenthash.New(defaultReplicas, nil)
+		},
+		newGetter: p.dial,
+		closeGetter: func(g *grpcGetter) {
+			_ = g.conn.Close()
+		},
+		logPick: func(peer, key string) {
+			if p.logRequests {
+				p.logger.Debug("pick peer", "peer", peer, "key", key)
+			}
+		},
+	}
+	return p
+}
+
+// SetLogger 设置记录日志使用的 slog.Logger，logRequests 为 true 时以 Debug 级别记录每个请求和每次节点选择
+// 需要在 Register 之前调用
+func (p *GRPCPool) SetLogger(logger *slog.Logger, logRequests bool) {
+	p.logger = logger.With("server", p.self)
+	p.logRequests = logRequests
+}
+
+// SetTracer 设置在节点之间传递追踪上下文使用的 Tracer，默认为 NoopTracer
+// 追踪上下文写入 gRPC metadata，一般与 Group 的 WithTracer 使用同一个实例，需要在 Register 和 Set 之前调用
+func (p *GRPCPool) SetTracer(tracer Tracer) {
+	p.tracer = tracer
+}
+
+// SetPlacement 设置节点选择策略，与 HTTPPoolOptions.Placement 相同，默认为一致性哈希环 consistenthash.Map
+// 需要在 Set 之前调用，每次 Set 都会调用 fn 创建新的实例
+func (p *GRPCPool) SetPlacement(fn func() consistenthash.Placement) {
+	p.newPlacement = fn
+}
+
+// Log 以 Info 级别打印日志
+func (p *GRPCPool) Log(format string, v ...interface{}) {
+	p.logger.Info(fmt.Sprintf(format, v...))
+}
+
+// Register 将 GroupCache 服务注册到 gRPC 服务器上
+func (p *GRPCPool) Register(s grpc.ServiceRegistrar) {
+	pb.RegisterGroupCacheServer(s, &grpcServer{pool: p})
+}
+
+// Set 实例化节点选择策略，添加传入的节点，并关闭旧节点的连接
+func (p *GRPCPool) Set(peers ...string) error {
+	weights := make(map[string]int, len(peers))
+	for _, peer := range peers {
+		weights[peer] = 1
+	}
+	return p.SetWeighted(weights)
+}
+
+// SetWeighted 与 Set 相同，但按权重分配 key，权重越大的节点分到的 key 越多
+// 选择策略未实现 consistenthash.WeightedPlacement 时忽略权重
+func (p *GRPCPool) SetWeighted(weights map[string]int) error {
+	return p.setWeighted(weights)
+}
+
+// AddPeer 增量添加节点，已存在的节点会被忽略，其余节点的 key 分配保持不变
+func (p *GRPCPool) AddPeer(peers ...string) error {
+	return p.addPeers(peers...)
+}
+
+// RemovePeer 增量删除节点并关闭连接，只有被删除节点上的 key 会迁移到其他节点
+func (p *GRPCPool) RemovePeer(peers ...string) {
+	p.removePeers(peers...)
+}
+
+// dial 创建连接到 peer 的客户端
+func (p *GRPCPool) dial(peer string) (*grpcGetter, error) {
+	conn, err := grpc.NewClient(peer, p.dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %v", peer, err)
+	}
+	return &grpcGetter{pool: p, addr: peer, conn: conn, client: pb.NewGroupCacheClient(conn)}, nil
+}
+
+// 静态类型检查
+var (
+	_ PeerPicker    = (*GRPCPool)(nil)
+	_ PeerLister    = (*GRPCPool)(nil)
+	_ ReplicaPicker = (*GRPCPool)(nil)
+)
+
+// grpcServer 实现了 zcachepb.GroupCacheServer，处理其他节点的请求
+type grpcServer struct {
+	pb.UnimplementedGroupCacheServer
+	pool *GRPCPool
+}
+
+// group 按名称查找 Group，不存在时返回 NotFound
+func (s *grpcServer) group(name string) (*Group, error) {
+	group := s.pool.lookup(name)
+	if group == nil {
+		return nil, toGRPC(fmt.Errorf("%w: %s", ErrNoSuchGroup, name))
+	}
+	return group, nil
+}
+
+// toGRPC 将 zcache 的错误类型转换为对应的 gRPC 状态码
+func toGRPC(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, ErrBadRequest):
+		code = codes.InvalidArgument
+	case errors.Is(err, ErrNoSuchGroup):
+		code = codes.NotFound
+	case errors.Is(err, ErrPeerUnavailable):
+		code = codes.Unavailable
+	}
+	return status.Error(code, err.Error())
+}
+
+// fromGRPC 将 gRPC 状态码转换为 zcache 的错误类型，status.Code 仍然可以取到原来的状态码
+func fromGRPC(err error) error {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return fmt.Errorf("%w: %w", ErrBadRequest, err)
+	case codes.NotFound:
+		return fmt.Errorf("%w: %w", ErrNoSuchGroup, err)
+	case codes.Unavailable:
+		return fmt.Errorf("%w: %w", ErrPeerUnavailable, err)
+	}
+	return err
+}
+
+// forwardedMetadata 与 HTTP 的 X-Zcache-Forwarded 头相同，标记请求由其他节点转发而来
+const forwardedMetadata = "x-zcache-forwarded"
+
+// requestContext 从请求的 metadata 中恢复追踪上下文并标记转发
+func (s *grpcServer) requestContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := make(http.Header, len(md))
+	for k, vs := range md {
+		header[http.CanonicalHeaderKey(k)] = vs
+	}
+	ctx = s.pool.tracer.Extract(ctx, header)
+	if len(md.Get(forwardedMetadata)) > 0 {
+		ctx = withForwarded(ctx)
+	}
+	return ctx
+}
+
+func (s *grpcServer) Get(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	if s.pool.logRequests {
+		s.pool.logger.Debug("serve request", "method", "Get", "group", in.GetGroup(), "key", in.GetKey())
+	}
+	group, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	group.stats.serverRequests.Add(1)
+	view, err := group.GetContext(s.requestContext(ctx), in.GetKey())
+	if err != nil {
+		return nil, toGRPC(err)
+	}
+	return &pb.Response{Value: view.ByteSlice(), Meta: group.entryMeta(view)}, nil
+}
+
+func (s *grpcServer) Set(ctx context.Context, in *pb.SetRequest) (*pb.SetResponse, error) {
+	group, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	group.handleSet(s.requestContext(ctx), in.GetKey(), in.GetValue())
+	return &pb.SetResponse{}, nil
+}
+
+func (s *grpcServer) Remove(ctx context.Context, in *pb.RemoveRequest) (*pb.RemoveResponse, error) {
+	group, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	group.handleRemove(s.requestContext(ctx), in.GetKey())
+	return &pb.RemoveResponse{}, nil
+}
+
+func (s *grpcServer) GetMulti(ctx context.Context, in *pb.MultiRequest) (*pb.MultiResponse, error) {
+	group, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	// 部分 key 获取失败时只返回成功的部分，由请求方决定如何处理缺少的 key
+	views, err := group.GetMulti(s.requestContext(ctx), in.GetKeys())
+	if err != nil {
+		s.pool.logger.Warn("get multi", "group", group.name, "err", err)
+	}
+	return group.multiResponse(views), nil
+}
+
+// grpcGetter 通过 gRPC 访问远程节点
+type grpcGetter struct {
+	pool   *GRPCPool
+	addr   string
+	conn   *grpc.ClientConn
+	client pb.GroupCacheClient
+}
+
+// PeerName 实现了 PeerNamer 接口，返回节点地址
+func (g *grpcGetter) PeerName() string {
+	return g.addr
+}
+
+// outgoingContext 将追踪上下文和转发标记写入请求的 metadata
+func (g *grpcGetter) outgoingContext(ctx context.Context) context.Context {
+	header := http.Header{}
+	g.pool.tracer.Inject(ctx, header)
+	kv := make([]string, 0, 2*len(header)+2)
+	for k, vs := range header {
+		for _, v := range vs {
+			kv = append(kv, strings.ToLower(k), v)
+		}
+	}
+	kv = append(kv, forwardedMetadata, "1")
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// Get 实现了 PeerGetter 接口的 Get() 方法
+func (g *grpcGetter) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	res, err := g.client.Get(g.outgoingContext(ctx), in)
+	if err != nil {
+		return fromGRPC(err)
+	}
+	proto.Merge(out, res)
+	return nil
+}
+
+// GetMulti 实现了 PeerBatchGetter 接口，一次请求获取多个 key
+func (g *grpcGetter) GetMulti(ctx context.Context, in *pb.MultiRequest, out *pb.MultiResponse) error {
+	res, err := g.client.GetMulti(g.outgoingContext(ctx), in)
+	if err != nil {
+		return fromGRPC(err)
+	}
+	proto.Merge(out, res)
+	return nil
+}
+
+// Set 实现了 PeerUpdater 接口，将缓存值写入远程节点
+func (g *grpcGetter) Set(ctx context.Context, in *pb.SetRequest) error {
+	_, err := g.client.Set(g.outgoingContext(ctx), in)
+	return fromGRPC(err)
+}
+
+// Remove 实现了 PeerUpdater 接口，删除远程节点上的缓存值
+func (g *grpcGetter) Remove(ctx context.Context, in *pb.RemoveRequest) error {
+	_, err := g.client.Remove(g.outgoingContext(ctx), in)
+	return fromGRPC(err)
+}
+
+// 静态类型检查
+var (
+	_ PeerGetter      = (*grpcGetter)(nil)
+	_ PeerUpdater     = (*grpcGetter)(nil)
+	_ PeerBatchGetter = (*grpcGetter)(nil)
+	_ PeerNamer       = (*grpcGetter)(nil)
+)
@@ -0,0 +1,201 @@
+package zcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+	"zcache/consistenthash"
+	pb "zcache/zcachepb"
+)
+
+// startGRPCPool 启动一个基于 bufconn 的 gRPC 服务，返回连接到它的 GRPCPool
+// 节点 "self" 代表本地，"passthrough:///bufnet" 代表远程节点
+func startGRPCPool(t *testing.T) *GRPCPool {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	t.Cleanup(s.Stop)
+
+	pool := NewGRPCPool("self",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	pool.Register(s)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	if err := pool.Set("self", "passthrough:///bufnet"); err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+// remoteKey 找到一个属于远程节点的 key
+func remoteKey(t *testing.T, picker PeerPicker) (string, PeerGetter) {
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if peer, ok := picker.PickPeer(key); ok {
+			return key, peer
+		}
+	}
+	t.Fatal("no remote key found")
+	return "", nil
+}
+
+func TestGRPCPool(t *testing.T) {
+	z := NewGroup("grpc", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			if key == "missing" {
+				return nil, fmt.Errorf("%s not found", key)
+			}
+			return []byte("value of " + key), nil
+		}))
+	pool := startGRPCPool(t)
+	key, peer := remoteKey(t, pool)
+	ctx := context.Background()
+
+	out := &pb.Response{}
+	if err := peer.Get(ctx, &pb.Request{Group: "grpc", Key: key}, out); err != nil {
+		t.Fatal(err)
+	}
+	if string(out.GetValue()) != "value of "+key {
+		t.Fatalf("unexpected value %q", out.GetValue())
+	}
+	if err := peer.Get(ctx, &pb.Request{Group: "no-such-group", Key: key}, &pb.Response{}); !errors.Is(err, ErrNoSuchGroup) {
+		t.Fatalf("expect ErrNoSuchGroup for unknown group, got %v", err)
+	}
+
+	multi := &pb.MultiResponse{}
+	if err := peer.(PeerBatchGetter).GetMulti(ctx, &pb.MultiRequest{Group: "grpc", Keys: []string{"a", "missing"}}, multi); err != nil {
+		t.Fatal(err)
+	}
+	if len(multi.GetValues()) != 1 || string(multi.GetValues()["a"]) != "value of a" {
+		t.Fatalf("unexpected batch response %v", multi.GetValues())
+	}
+
+	updater := peer.(PeerUpdater)
+	if err := updater.Set(ctx, &pb.SetRequest{Group: "grpc", Key: "pushed", Value: []byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := z.mainCache.get("pushed"); !ok || v.String() != "v" {
+		t.Fatalf("Set should populate the cache, got %q, %v", v.String(), ok)
+	}
+	if err := updater.Remove(ctx, &pb.RemoveRequest{Group: "grpc", Key: "pushed"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := z.mainCache.get("pushed"); ok {
+		t.Fatalf("Remove should delete the key")
+	}
+	if len(pool.Peers()) != 1 {
+		t.Fatalf("expect 1 remote peer, got %d", len(pool.Peers()))
+	}
+}
+
+// startGRPCNodes 启动两个基于 bufconn 的节点 "a" 和 "b"，返回节点 a 使用的 GRPCPool
+// 测试在同一进程中运行，节点 b 的 lookup 把 name 映射到 remote，避免与节点 a 共享同一个 Group
+func startGRPCNodes(t *testing.T, name string, remote *Group) *GRPCPool {
+	listeners := map[string]*bufconn.Listener{}
+	dialer := grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		return listeners[addr].DialContext(ctx)
+	})
+	for _, node := range []string{"a", "b"} {
+		lis := bufconn.Listen(1 << 20)
+		listeners[node] = lis
+		s := grpc.NewServer()
+		t.Cleanup(s.Stop)
+		pool := NewGRPCPool(node)
+		if node == "b" {
+			pool.lookup = func(group string) *Group {
+				if group == name {
+					return remote
+				}
+				return nil
+			}
+		}
+		pool.Register(s)
+		go func() {
+			_ = s.Serve(lis)
+		}()
+	}
+	pool := NewGRPCPool("passthrough:///a", grpc.WithTransportCredentials(insecure.NewCredentials()), dialer)
+	if err := pool.Set("passthrough:///a", "passthrough:///b"); err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func TestGRPCGroupGet(t *testing.T) {
+	remote := NewGroup("grpc-e2e-b", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("b:" + key), nil
+		}))
+	local := NewGroup("grpc-e2e", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("a:" + key), nil
+		}))
+	pool := startGRPCNodes(t, "grpc-e2e", remote)
+	local.RegisterPeers(pool)
+
+	key, _ := remoteKey(t, pool)
+	view, err := local.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.String() != "b:"+key {
+		t.Fatalf("expect the owner to load %q, got %q", key, view.String())
+	}
+	if remote.Stats().ServerRequests != 1 || remote.Stats().LocalLoads != 1 {
+		t.Fatalf("expect the owner to serve and load the key once, got %+v", remote.Stats())
+	}
+	if _, ok := local.mainCache.get(key); ok {
+		t.Fatalf("remote keys should not be stored in the main cache")
+	}
+}
+
+func TestGRPCPoolPeers(t *testing.T) {
+	pool := NewGRPCPool("self")
+	pool.SetPlacement(func() consistenthash.Placement {
+		return consistenthash.NewRendezvous(nil)
+	})
+	if err := pool.SetWeighted(map[string]int{"self": 1, "b": 1, "c": 1}); err != nil {
+		t.Fatal(err)
+	}
+	peers := pool.PickPeers("key", 3)
+	if len(peers) != 3 {
+		t.Fatalf("expect 3 replicas, got %d", len(peers))
+	}
+	remote := 0
+	for _, peer := range peers {
+		if peer != nil {
+			remote++
+		}
+	}
+	if remote != 2 {
+		t.Fatalf("expect 2 remote replicas, got %d", remote)
+	}
+	if first, ok := pool.PickPeer("key"); ok != (peers[0] != nil) || ok && first != peers[0] {
+		t.Fatalf("the first replica should match PickPeer")
+	}
+
+	if err := pool.AddPeer("d", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if len(pool.Peers()) != 3 {
+		t.Fatalf("expect 3 remote peers after AddPeer, got %d", len(pool.Peers()))
+	}
+	pool.RemovePeer("b", "c", "d")
+	for i := 0; i < 100; i++ {
+		if _, ok := pool.PickPeer(fmt.Sprintf("key%d", i)); ok {
+			t.Fatalf("all keys should belong to self after RemovePeer")
+		}
+	}
+	if len(pool.Peers()) != 0 {
+		t.Fatalf("expect no remote peers, got %d", len(pool.Peers()))
+	}
+}
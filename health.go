@@ -0,0 +1,112 @@
+package zcache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	healthPath                = "/healthz"
+	defaultHealthCheckTimeout = time.Second
+	defaultFailureThreshold   = 3
+)
+
+// peerHealth 记录一个节点的健康状态
+type peerHealth struct {
+	failures int  // 连续失败的次数
+	ejected  bool // 是否已被移出节点选择
+}
+
+// healthCheck 定期探测其他节点，直到 StopHealthCheck 被调用
+func (p *HTTPPool) healthCheck() {
+	ticker := time.NewTicker(p.opts.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.probeAll()
+		case <-p.stopHealth:
+			return
+		}
+	}
+}
+
+// StopHealthCheck 停止后台健康检查，可以重复调用
+func (p *HTTPPool) StopHealthCheck() {
+	p.stopOnce.Do(func() {
+		close(p.stopHealth)
+	})
+}
+
+// probeAll 并发探测所有其他节点，并根据结果更新健康状态
+func (p *HTTPPool) probeAll() {
+	p.mu.Lock()
+	peers := make([]string, 0, len(p.health))
+	for peer := range p.health {
+		if peer != p.self {
+			peers = append(peers, peer)
+		}
+	}
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, peer := range peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.reportHealth(peer, p.probe(peer))
+		}()
+	}
+	wg.Wait()
+}
+
+// probe 请求节点的 healthz 接口
+func (p *HTTPPool) probe(peer string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.HealthCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, peer+p.basePath+healthPath, nil)
+	if err != nil {
+		return err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check returned: %v", res.Status)
+	}
+	return nil
+}
+
+// reportHealth 记录一次探测或请求的结果
+// 连续失败达到阈值时将节点移出节点选择，之后任意一次成功都会让它重新加入
+func (p *HTTPPool) reportHealth(peer string, err error) {
+	if p.opts.HealthCheckInterval <= 0 || peer == p.self {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h, ok := p.health[peer]
+	if !ok {
+		return
+	}
+	if err == nil {
+		h.failures = 0
+		if h.ejected {
+			h.ejected = false
+			p.readmit(peer)
+			p.logger.Info("peer is healthy again", "peer", peer)
+		}
+		return
+	}
+	h.failures++
+	if !h.ejected && h.failures >= p.opts.FailureThreshold {
+		h.ejected = true
+		p.eject(peer)
+		p.logger.Warn("peer ejected", "peer", peer, "failures", h.failures, "err", err)
+	}
+}
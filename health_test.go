@@ -0,0 +1,138 @@
+package zcache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	pb "zcache/zcachepb"
+)
+
+// waitFor 等待 cond 成立，超时则测试失败
+func waitFor(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", msg)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// remoteShare 返回被分配给远程节点的 key 的数量
+func remoteShare(picker PeerPicker) int {
+	n := 0
+	for i := 0; i < 100; i++ {
+		if _, ok := picker.PickPeer(fmt.Sprintf("key%d", i)); ok {
+			n++
+		}
+	}
+	return n
+}
+
+func TestHealthCheck(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{
+		HealthCheckInterval: time.Millisecond,
+		FailureThreshold:    2,
+	})
+	defer pool.StopHealthCheck()
+	pool.Set("http://self", srv.URL)
+	before := remoteShare(pool)
+	if before == 0 {
+		t.Fatalf("expect some keys on the remote peer")
+	}
+
+	healthy.Store(false)
+	waitFor(t, "ejection", func() bool { return !pool.Healthy(srv.URL) })
+	if n := remoteShare(pool); n != 0 {
+		t.Fatalf("ejected peer should not be picked, got %d keys", n)
+	}
+
+	healthy.Store(true)
+	waitFor(t, "re-admission", func() bool { return pool.Healthy(srv.URL) })
+	if n := remoteShare(pool); n != before {
+		t.Fatalf("re-admitted peer should get its keys back, %d != %d", n, before)
+	}
+}
+
+func TestPassiveHealth(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	dead := srv.URL
+	srv.Close()
+
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{
+		HealthCheckInterval: time.Hour,
+		FailureThreshold:    1,
+	})
+	defer pool.StopHealthCheck()
+	pool.Set("http://self", dead)
+
+	var key string
+	var peer PeerGetter
+	for i := 0; peer == nil; i++ {
+		key = fmt.Sprintf("key%d", i)
+		peer, _ = pool.PickPeer(key)
+	}
+	if err := peer.Get(context.Background(), &pb.Request{Group: "g", Key: key}, &pb.Response{}); err == nil {
+		t.Fatalf("expect error from a dead peer")
+	}
+	if pool.Healthy(dead) || remoteShare(pool) != 0 {
+		t.Fatalf("failed request should eject the peer")
+	}
+}
+
+func TestPassiveHealthStatus(t *testing.T) {
+	// healthz 正常，但请求返回 503
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, healthPath) {
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{
+		HealthCheckInterval: time.Hour,
+		FailureThreshold:    1,
+	})
+	defer pool.StopHealthCheck()
+	pool.Set("http://self", srv.URL)
+
+	key, peer := remoteKey(t, pool)
+	if err := peer.Get(context.Background(), &pb.Request{Group: "g", Key: key}, &pb.Response{}); err == nil {
+		t.Fatalf("expect error from an overloaded peer")
+	}
+	if pool.Healthy(srv.URL) || remoteShare(pool) != 0 {
+		t.Fatalf("503 should count as a failure and eject the peer")
+	}
+}
+
+func TestHealthzEndpoint(t *testing.T) {
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+	res, err := http.Get(srv.URL + defaultBasePath + healthPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expect 200 from healthz, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,196 @@
+package zcache
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHedgeDelay = 10 * time.Millisecond // 耗时样本不足时的对冲等待时间
+	hedgeQuantile     = 0.95                  // 按最近请求耗时的 p95 计算对冲等待时间
+	latencySamples    = 128                   // 保留的耗时样本数
+	minLatencySamples = 16                    // 样本数少于该值时使用 defaultHedgeDelay
+	maxBackoffShift   = 16                    // 指数退避的最大倍数为 2^16
+)
+
+// WithHedging 开启对冲请求：远程节点在 delay 内没有返回时，向下一个副本节点再发送一次请求，采用先返回的结果
+// delay 小于等于 0 时使用最近远程请求耗时的 p95，样本不足时为 10ms
+// 需要配合 WithReplication 使用，只有一个远程副本时不生效
+func WithHedging(delay time.Duration) GroupOption {
+	return func(g *Group) {
+		g.hedging = true
+		g.hedgeDelay = max(delay, 0)
+		if delay <= 0 {
+			g.latency = &latencyWindow{}
+		}
+	}
+}
+
+// WithRetry 远程节点返回暂时性错误（网络错误、502/503/504、gRPC Unavailable）时最多重试 n 次
+// 第 i 次重试前随机等待 [0, backoff*2^(i-1)] 的时间，避免多个节点同时重试
+func WithRetry(n int, backoff time.Duration) GroupOption {
+	return func(g *Group) {
+		g.retries = n
+		g.retryBackoff = backoff
+	}
+}
+
+// getFromOwners 从远程副本节点获取数据，peers 按优先级排列且不包含本节点
+// 未开启对冲时按顺序尝试，开启后前一个节点超过对冲等待时间仍未返回就同时请求下一个节点
+// 任意节点失败时立即改为请求下一个节点，全部失败时返回汇总的错误
+func (g *Group) getFromOwners(ctx context.Context, peers []PeerGetter, key string) (ByteView, error) {
+	if !g.hedging || len(peers) == 1 {
+		var errs []error
+		for _, peer := range peers {
+			value, err := g.getFromPeerRetry(ctx, peer, key)
+			if err == nil {
+				return value, nil
+			}
+			g.logPeerError(peer, key, err)
+			errs = append(errs, err)
+		}
+		return ByteView{}, errors.Join(errs...)
+	}
+
+	// 先返回的结果胜出后取消其余请求
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		peer  PeerGetter
+		value ByteView
+		err   error
+	}
+	results := make(chan result, len(peers))
+	next := 0
+	launch := func() {
+		peer := peers[next]
+		next++
+		go func() {
+			value, err := g.getFromPeerRetry(ctx, peer, key)
+			results <- result{peer, value, err}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(g.hedgeAfter())
+	defer timer.Stop()
+	var errs []error
+	for pending := 1; pending > 0; {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				return r.value, nil
+			}
+			g.logPeerError(r.peer, key, r.err)
+			errs = append(errs, r.err)
+			if next < len(peers) {
+				launch()
+				pending++
+			}
+		case <-timer.C:
+			if next < len(peers) {
+				launch()
+				pending++
+				timer.Reset(g.hedgeAfter())
+			}
+		}
+	}
+	return ByteView{}, errors.Join(errs...)
+}
+
+// getFromPeerRetry 请求远程节点，遇到暂时性错误时按退避时间重试
+func (g *Group) getFromPeerRetry(ctx context.Context, peer PeerGetter, key string) (ByteView, error) {
+	for attempt := 0; ; attempt++ {
+		value, err := g.getFromPeer(ctx, peer, key)
+		if err == nil || attempt >= g.retries || ctx.Err() != nil || !transient(err) {
+			return value, err
+		}
+		timer := time.NewTimer(g.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ByteView{}, err
+		}
+	}
+}
+
+// backoff 第 attempt+1 次重试前的等待时间，在 [0, retryBackoff*2^attempt] 内随机选取
+func (g *Group) backoff(attempt int) time.Duration {
+	if g.retryBackoff <= 0 {
+		return 0
+	}
+	d := g.retryBackoff << min(attempt, maxBackoffShift)
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// hedgeAfter 返回发送下一个对冲请求前的等待时间
+func (g *Group) hedgeAfter() time.Duration {
+	if g.hedgeDelay > 0 {
+		return g.hedgeDelay
+	}
+	if d, ok := g.latency.quantile(hedgeQuantile); ok {
+		return d
+	}
+	return defaultHedgeDelay
+}
+
+// transient 判断远程节点返回的错误是否是暂时性的，值得重试
+func transient(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	if errors.Is(err, ErrPeerUnavailable) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	return status.Code(err) == codes.Unavailable
+}
+
+// logPeerError 记录远程节点的错误，熔断器拒绝的请求不打印日志
+func (g *Group) logPeerError(peer PeerGetter, key string, err error) {
+	if !errors.Is(err, ErrCircuitOpen) {
+		g.logger.Warn("远程节点获取数据失败", "peer", peerName(peer), "key", key, "err", err)
+	}
+}
+
+// latencyWindow 保存最近若干次远程请求的耗时
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples [latencySamples]time.Duration
+	n       int // 已保存的样本数
+	next    int // 下一个样本写入的位置
+}
+
+func (w *latencyWindow) observe(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % latencySamples
+	if w.n < latencySamples {
+		w.n++
+	}
+}
+
+// quantile 返回耗时的 q 分位数，样本不足时返回 false
+func (w *latencyWindow) quantile(q float64) (time.Duration, bool) {
+	w.mu.Lock()
+	samples := slices.Clone(w.samples[:w.n])
+	w.mu.Unlock()
+	if len(samples) < minLatencySamples {
+		return 0, false
+	}
+	slices.Sort(samples)
+	return samples[int(q*float64(len(samples)-1))], true
+}
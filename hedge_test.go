@@ -0,0 +1,156 @@
+package zcache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+	pb "zcache/zcachepb"
+)
+
+// slowPeer 等待 delay 后返回 value，期间 ctx 被取消则返回错误
+type slowPeer struct {
+	delay time.Duration
+	value string
+	calls atomic.Int32
+}
+
+func (p *slowPeer) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	p.calls.Add(1)
+	select {
+	case <-time.After(p.delay):
+		out.Value = []byte(p.value)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flakyPeer 前 failures 次请求返回 err
+type flakyPeer struct {
+	failures int32
+	err      error
+	calls    atomic.Int32
+}
+
+func (p *flakyPeer) Get(_ context.Context, in *pb.Request, out *pb.Response) error {
+	if p.calls.Add(1) <= p.failures {
+		return p.err
+	}
+	out.Value = []byte("flaky")
+	return nil
+}
+
+func TestHedging(t *testing.T) {
+	slow := &slowPeer{delay: time.Second, value: "slow"}
+	fast := &slowPeer{value: "fast"}
+	z := NewGroup("hedging", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return nil, errors.New("should not load locally")
+		}),
+		WithReplication(2, false),
+		WithHedging(10*time.Millisecond),
+	)
+	z.RegisterPeers(&replicaPicker{owners: []PeerGetter{slow, fast}})
+
+	start := time.Now()
+	v, err := z.Get("key")
+	if err != nil || v.String() != "fast" {
+		t.Fatalf("expect hedged value, got %q, %v", v.String(), err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("hedged request should not wait for the slow peer, took %v", d)
+	}
+	if slow.calls.Load() != 1 || fast.calls.Load() != 1 {
+		t.Fatalf("expect one request per replica, got %d and %d", slow.calls.Load(), fast.calls.Load())
+	}
+}
+
+func TestHedgingNegativeDelay(t *testing.T) {
+	slow := &slowPeer{delay: time.Second, value: "slow"}
+	fast := &slowPeer{value: "fast"}
+	z := NewGroup("hedging-negative", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return nil, errors.New("should not load locally")
+		}),
+		WithReplication(2, false),
+		WithHedging(-time.Second),
+	)
+	z.RegisterPeers(&replicaPicker{owners: []PeerGetter{slow, fast}})
+	// 负数与 0 相同，样本不足时使用 defaultHedgeDelay
+	if v, err := z.Get("key"); err != nil || v.String() != "fast" {
+		t.Fatalf("expect hedged value, got %q, %v", v.String(), err)
+	}
+	if z.hedgeAfter() != defaultHedgeDelay {
+		t.Fatalf("expect the default delay before enough samples, got %v", z.hedgeAfter())
+	}
+}
+
+func TestHedgingNotNeeded(t *testing.T) {
+	first := &slowPeer{value: "first"}
+	second := &slowPeer{value: "second"}
+	z := NewGroup("hedging-fast", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return nil, errors.New("should not load locally")
+		}),
+		WithReplication(2, false),
+		WithHedging(time.Second),
+	)
+	z.RegisterPeers(&replicaPicker{owners: []PeerGetter{first, second}})
+	if v, err := z.Get("key"); err != nil || v.String() != "first" {
+		t.Fatalf("expect value from the first replica, got %q, %v", v.String(), err)
+	}
+	if second.calls.Load() != 0 {
+		t.Fatalf("fast replies should not be hedged")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	unavailable := &statusError{code: http.StatusServiceUnavailable, status: "503 Service Unavailable"}
+	loads := 0
+	z := NewGroup("retry", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte("origin"), nil
+		}),
+		WithRetry(2, time.Millisecond),
+	)
+	peer := &flakyPeer{failures: 2, err: unavailable}
+	z.RegisterPeers(&replicaPicker{owners: []PeerGetter{peer}})
+	if v, err := z.Get("a"); err != nil || v.String() != "flaky" || loads != 0 {
+		t.Fatalf("expect value after retries, got %q, %v, loads=%d", v.String(), err, loads)
+	}
+	if n := peer.calls.Load(); n != 3 {
+		t.Fatalf("expect 3 attempts, got %d", n)
+	}
+
+	// 重试次数用完后在本地加载
+	peer.calls.Store(0)
+	peer.failures = 3
+	if v, err := z.Get("b"); err != nil || v.String() != "origin" || peer.calls.Load() != 3 {
+		t.Fatalf("expect local load after retries, got %q, %v, calls=%d", v.String(), err, peer.calls.Load())
+	}
+
+	// 非暂时性错误不重试
+	peer.calls.Store(0)
+	peer.err = &statusError{code: http.StatusInternalServerError, status: "500 Internal Server Error"}
+	if _, err := z.Get("c"); err != nil || peer.calls.Load() != 1 {
+		t.Fatalf("permanent errors should not be retried, calls=%d", peer.calls.Load())
+	}
+}
+
+func TestLatencyWindow(t *testing.T) {
+	w := &latencyWindow{}
+	if _, ok := w.quantile(hedgeQuantile); ok {
+		t.Fatalf("empty window should not report a quantile")
+	}
+	for i := 1; i <= 2*latencySamples; i++ {
+		w.observe(time.Duration(i) * time.Millisecond)
+	}
+	// 只保留最近的 128 个样本：129ms..256ms
+	if d, ok := w.quantile(hedgeQuantile); !ok || d < 240*time.Millisecond || d > 256*time.Millisecond {
+		t.Fatalf("unexpected p95 %v", d)
+	}
+}
@@ -1,163 +1,716 @@
 package zcache
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"google.golang.org/protobuf/proto"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 	"zcache/consistenthash"
 	pb "zcache/zcachepb"
 )
 
 const (
-	defaultBasePath = "/zcache"
-	defaultReplicas = 50
+	defaultBasePath     = "/zcache"
+	defaultReplicas     = 50
+	maxRequestBodyBytes = 64 << 20 // 其他节点发来的请求体的最大大小，与是否签名无关
+)
+
+// 节点间通讯协议
+// 请求和响应都带有 X-Zcache-Protocol 头标明协议版本，GET 默认返回 proto 编码的 Response，
+// 请求头 Accept 为 application/octet-stream 时直接返回原始的缓存值，方便人工调试
+// 节点转发的请求带有 X-Zcache-Forwarded 头，收到的节点未命中时直接在本地加载，不再转发
+const (
+	protocolHeader   = "X-Zcache-Protocol"
+	forwardedHeader  = "X-Zcache-Forwarded"
+	protocolVersion  = "1"
+	contentTypeProto = "application/x-protobuf"
+	contentTypeRaw   = "application/octet-stream"
 )
 
 // HTTPPool 实现 http 请求的分布式缓存服务 api
 type HTTPPool struct {
-	self        string                 // 记录地址
-	basePath    string                 // 通讯地址
-	mu          sync.Mutex             // guards peers and httpGetters
-	httpGetters map[string]*httpGetter // keyed by e.g. "http://10.0.0.2:8008"
-	peers       *consistenthash.Map
+	peerSet[*httpGetter] // 节点成员，keyed by e.g. "http://10.0.0.2:8008"
+
+	self       string          // 记录地址
+	basePath   string          // 通讯地址
+	opts       HTTPPoolOptions // 可选配置
+	client     *http.Client    // 请求其他节点使用的客户端
+	logger     *slog.Logger    // 带有 server 属性的日志
+	stopHealth chan struct{}   // 关闭后停止健康检查
+	stopOnce   sync.Once
+}
+
+// HTTPPoolOptions HTTPPool 的可选配置，零值字段使用默认值
+type HTTPPoolOptions struct {
+	// BasePath 通讯地址的前缀，默认为 "/zcache"
+	BasePath string
+
+	// Replicas 实节点对应虚拟节点的倍数，默认为 50
+	Replicas int
+
+	// LoadFactor 大于 0 时开启有界负载模式，
+	// 发往某个节点的进行中请求超过平均值的 1+LoadFactor 倍时，key 会被分配给哈希环上的下一个节点
+	// 负载只统计本节点发出的请求，各节点选出的节点可能不同；收到转发请求的节点直接在本地加载，不会再次转发
+	// 只对实现了 consistenthash.BoundedPlacement 的选择策略生效
+	LoadFactor float64
+
+	// Placement 创建节点选择策略，默认为一致性哈希环 consistenthash.Map，
+	// 也可以使用 consistenthash.NewRendezvous、NewJump、NewMaglev 等实现
+	// 每次 Set 都会调用它创建新的实例
+	Placement func() consistenthash.Placement
+
+	// HealthCheckInterval 大于 0 时开启健康检查：定期探测其他节点的 <BasePath>/healthz，
+	// 连续失败的节点会被暂时移出节点选择，探测成功后重新加入
+	// 请求其他节点时的网络错误同样计入失败次数
+	HealthCheckInterval time.Duration
+
+	// HealthCheckTimeout 单次探测的超时时间，默认为 1 秒
+	HealthCheckTimeout time.Duration
+
+	// FailureThreshold 连续失败多少次后移除节点，默认为 3
+	FailureThreshold int
+
+	// Breaker 不为 nil 时为每个远程节点启用熔断器，
+	// 熔断器打开期间发往该节点的请求立即返回 ErrCircuitOpen，Group 直接在本地加载
+	Breaker *BreakerOptions
+
+	// Client 请求其他节点使用的客户端，设置后忽略 Transport、MaxIdleConnsPerHost 和 H2C
+	Client *http.Client
+
+	// Transport 请求其他节点使用的 RoundTripper，设置后忽略 MaxIdleConnsPerHost 和 H2C
+	// 两者都为 nil 时基于 http.DefaultTransport 创建
+	Transport http.RoundTripper
+
+	// Timeout 大于 0 时限制每次请求其他节点的总时长，包括读取响应体
+	// 超时的请求与网络错误一样计入节点的失败次数
+	Timeout time.Duration
+
+	// MaxIdleConnsPerHost 与每个节点保持的最大空闲连接数，默认使用 http.DefaultTransport 的设置
+	MaxIdleConnsPerHost int
+
+	// H2C 为 true 时节点之间使用不加密的 HTTP/2 通讯，所有节点的服务端都需要使用 Protocols() 开启
+	// 与 TLS 同时使用时 https:// 地址使用基于 TLS 的 HTTP/2
+	H2C bool
+
+	// TLS 不为 nil 时节点之间使用 mTLS 通讯，服务端需要使用 TLSConfig() 并只接受验证通过的客户端证书
+	// 节点地址应使用 https://，设置了 Client 或 Transport 时需要自行配置客户端证书
+	TLS *TLSOptions
+
+	// Secret 不为空时请求其他节点会带上 HMAC-SHA256 签名，签名覆盖方法、路径、查询参数和请求体
+	// 服务端拒绝签名无效或时间戳偏差超过 5 分钟的请求，但不防止这 5 分钟内的重放，需要时应同时使用 TLS
+	// 所有节点必须使用相同的 Secret，可以与 TLS 同时使用
+	Secret []byte
+
+	// Logger 记录日志使用的 slog.Logger，默认为 slog.Default()，日志级别由它的 Handler 决定
+	Logger *slog.Logger
+
+	// LogRequests 为 true 时以 Debug 级别记录每个请求和每次节点选择，默认关闭
+	LogRequests bool
+
+	// Tracer 在发往其他节点的请求头中写入追踪上下文，并在收到请求时恢复，默认为 NoopTracer
+	// 一般与 Group 的 WithTracer 使用同一个实例
+	Tracer Tracer
 }
 
 // NewHTTPPool 创建一个新的 HTTPPool
 func NewHTTPPool(self string) *HTTPPool {
-	return &HTTPPool{
-		self:     self,
-		basePath: defaultBasePath,
+	return NewHTTPPoolOpts(self, nil)
+}
+
+// NewHTTPPoolOpts 使用自定义配置创建 HTTPPool，o 为 nil 时使用默认配置
+func NewHTTPPoolOpts(self string, o *HTTPPoolOptions) *HTTPPool {
+	p := &HTTPPool{self: self}
+	if o != nil {
+		p.opts = *o
+	}
+	if p.opts.BasePath == "" {
+		p.opts.BasePath = defaultBasePath
+	}
+	if p.opts.Replicas == 0 {
+		p.opts.Replicas = defaultReplicas
+	}
+	if p.opts.Placement == nil {
+		p.opts.Placement = func() consistenthash.Placement {
+			return consistenthash.New(p.opts.Replicas, nil)
+		}
+	}
+	if p.opts.HealthCheckTimeout == 0 {
+		p.opts.HealthCheckTimeout = defaultHealthCheckTimeout
+	}
+	if p.opts.FailureThreshold == 0 {
+		p.opts.FailureThreshold = defaultFailureThreshold
+	}
+	if p.opts.Logger == nil {
+		p.opts.Logger = slog.Default()
+	}
+	if p.opts.Tracer == nil {
+		p.opts.Tracer = NoopTracer{}
+	}
+	p.logger = p.opts.Logger.With("server", self)
+	p.basePath = p.opts.BasePath
+	p.client = p.newClient()
+	p.peerSet = peerSet[*httpGetter]{
+		self:         self,
+		newPlacement: p.boundedPlacement,
+		newGetter:    p.newGetter,
+		logPick: func(peer, key string) {
+			if p.opts.LogRequests {
+				p.logger.Debug("pick peer", "peer", peer, "key", key)
+			}
+		},
+	}
+	p.stopHealth = make(chan struct{})
+	if p.opts.HealthCheckInterval > 0 {
+		go p.healthCheck()
 	}
+	return p
 }
 
-// Log 打印日志
+// newClient 根据配置创建请求其他节点使用的客户端
+func (p *HTTPPool) newClient() *http.Client {
+	if p.opts.Client != nil {
+		return p.opts.Client
+	}
+	if p.opts.Transport != nil {
+		return &http.Client{Transport: p.opts.Transport}
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if p.opts.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = p.opts.MaxIdleConnsPerHost
+	}
+	if p.opts.H2C {
+		// 不包含 HTTP1 时 http:// 使用不加密的 HTTP/2，https:// 仍然可以使用 HTTP/2
+		t.Protocols = new(http.Protocols)
+		t.Protocols.SetHTTP2(true)
+		t.Protocols.SetUnencryptedHTTP2(true)
+	}
+	if p.opts.TLS != nil {
+		t.TLSClientConfig = p.clientTLSConfig()
+	}
+	return &http.Client{Transport: t}
+}
+
+// Protocols 返回服务端应开启的协议，即 http.Server 默认的 HTTP/1 和基于 TLS 的 HTTP/2，开启 H2C 时额外支持不加密的 HTTP/2
+// 例如 srv := &http.Server{Handler: pool, Protocols: pool.Protocols()}
+func (p *HTTPPool) Protocols() *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(p.opts.H2C)
+	return protocols
+}
+
+// Log 以 Info 级别打印日志
 func (p *HTTPPool) Log(format string, v ...interface{}) {
-	log.Printf("[Server %s] %s", p.self, fmt.Sprintf(format, v...))
+	p.logger.Info(fmt.Sprintf(format, v...))
 }
 
-// ServeHTTP 处理 http 请求
+// ServeHTTP 处理 http 请求，处理过程中的 panic 会被恢复并返回 500
 func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if !strings.HasPrefix(r.URL.Path, p.basePath) {
-		panic("invalid path: " + r.URL.Path)
+	defer recoverPanic(w, r, p.logger)
+	if !strings.HasPrefix(r.URL.Path, p.basePath+"/") {
+		httpError(w, fmt.Errorf("%w: invalid path: %s", ErrBadRequest, r.URL.Path))
+		return
+	}
+
+	w.Header().Set(protocolHeader, protocolVersion)
+	if r.URL.Path == p.basePath+healthPath {
+		_, _ = io.WriteString(w, "ok")
+		return
+	}
+
+	if p.opts.LogRequests {
+		p.logger.Debug("serve request", "method", r.Method, "path", r.URL.Path)
+	}
+
+	if err := p.authenticate(w, r); err != nil {
+		httpError(w, err)
+		return
 	}
 
-	p.Log("%s %s", r.Method, r.URL.Path)
+	if v := r.Header.Get(protocolHeader); v != "" && v != protocolVersion {
+		httpError(w, fmt.Errorf("%w: unsupported protocol version: %s", ErrBadRequest, v))
+		return
+	}
 
-	// 路径格式 /<basePath>/<groupName>/<key>
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) != 4 {
-		http.Error(w, "bad request", http.StatusBadRequest)
+	// 路径格式 /<basePath>/<groupName>/<key>，key 中可以包含 /
+	parts := strings.SplitN(r.URL.Path[len(p.basePath)+1:], "/", 2)
+	if len(parts) != 2 {
+		httpError(w, fmt.Errorf("%w: invalid path: %s", ErrBadRequest, r.URL.Path))
 		return
 	}
 
-	groupName := parts[2]
-	key := parts[3]
+	groupName := parts[0]
+	key := parts[1]
 
 	group := GetGroup(groupName)
 	if group == nil {
-		msg := fmt.Sprintf("no such group: %s", groupName)
-		http.Error(w, msg, http.StatusNotFound)
+		httpError(w, fmt.Errorf("%w: %s", ErrNoSuchGroup, groupName))
 		return
 	}
 
-	view, err := group.Get(key)
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		p.serveSet(w, r, group, key)
+		return
+	case http.MethodPost:
+		p.serveMulti(w, r, group)
+		return
+	case http.MethodDelete:
+		group.handleRemove(p.requestContext(r), key)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group.stats.serverRequests.Add(1)
+	view, err := group.GetContext(p.requestContext(r), key)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err)
+		return
+	}
+
+	// 响应体只写入一次：原始的缓存值或 proto 编码的 Response
+	body := view.ByteSlice()
+	contentType := contentTypeRaw
+	if !acceptsRaw(r) {
+		body, err = proto.Marshal(&pb.Response{Value: body, Meta: group.entryMeta(view)})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		contentType = contentTypeProto
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(body)
+}
+
+// requestContext 返回处理其他节点请求使用的 ctx，恢复追踪上下文并标记转发
+func (p *HTTPPool) requestContext(r *http.Request) context.Context {
+	ctx := p.opts.Tracer.Extract(r.Context(), r.Header)
+	if r.Header.Get(forwardedHeader) != "" {
+		ctx = withForwarded(ctx)
+	}
+	return ctx
+}
+
+// httpError 按错误类型返回对应的状态码
+func httpError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), httpStatus(err))
+}
+
+// Recover 包装 handler，将处理过程中的 panic 转换为 500 响应并打印日志，避免单个请求拖垮整个服务
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer recoverPanic(w, r, slog.Default())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recoverPanic 需要被 defer 调用，http.ErrAbortHandler 会继续向上抛出
+func recoverPanic(w http.ResponseWriter, r *http.Request, logger *slog.Logger) {
+	err := recover()
+	if err == nil {
 		return
 	}
+	if err == http.ErrAbortHandler {
+		panic(err)
+	}
+	logger.Error("panic serving request", "method", r.Method, "path", r.URL.Path, "panic", err, "stack", string(debug.Stack()))
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
 
-	w.Header().Set("Content-Type", "application/octet-stream")
-	_, err = w.Write(view.ByteSlice())
+// acceptsRaw 判断请求方是否要求直接返回原始的缓存值
+func acceptsRaw(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.TrimSpace(mediaType) == contentTypeRaw {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serveSet 处理其他节点推送的缓存值，请求体为 proto 编码的 SetRequest
+func (p *HTTPPool) serveSet(w http.ResponseWriter, r *http.Request, group *Group, key string) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	in := &pb.SetRequest{}
+	if err = proto.Unmarshal(body, in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	group.handleSet(p.requestContext(r), key, in.GetValue())
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	// 将值作为 proto 消息写入响应体
-	body, err := proto.Marshal(&pb.Response{Value: view.ByteSlice()})
+// serveMulti 处理批量获取请求，路径为 /<basePath>/<groupName>/，请求体为 proto 编码的 MultiRequest
+func (p *HTTPPool) serveMulti(w http.ResponseWriter, r *http.Request, group *Group) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	in := &pb.MultiRequest{}
+	if err = proto.Unmarshal(body, in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	w.Header().Set("Content-Type", "application/octet-stream")
-	_, err = w.Write(body)
+	// 部分 key 获取失败时只返回成功的部分，由请求方决定如何处理缺少的 key
+	views, err := group.GetMulti(p.requestContext(r), in.GetKeys())
+	if err != nil {
+		p.logger.Warn("get multi", "group", group.name, "err", err)
+	}
+	body, err = proto.Marshal(group.multiResponse(views))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", contentTypeProto)
+	_, _ = w.Write(body)
 }
 
 // Set 实例化一致性哈希算法，并且添加了传入的节点
 func (p *HTTPPool) Set(peers ...string) {
+	weights := make(map[string]int, len(peers))
+	for _, peer := range peers {
+		weights[peer] = 1
+	}
+	p.SetWeighted(weights)
+}
+
+// SetWeighted 与 Set 相同，但按权重分配 key，权重越大的节点分到的 key 越多
+// 选择策略未实现 consistenthash.WeightedPlacement 时忽略权重
+func (p *HTTPPool) SetWeighted(weights map[string]int) {
+	_ = p.setWeighted(weights)
+}
+
+// boundedPlacement 按配置创建节点选择策略，并设置有界负载系数
+func (p *HTTPPool) boundedPlacement() consistenthash.Placement {
+	placement := p.opts.Placement()
+	if bp, ok := placement.(consistenthash.BoundedPlacement); ok {
+		bp.SetLoadFactor(p.opts.LoadFactor)
+	}
+	return placement
+}
+
+func (p *HTTPPool) newGetter(peer string) (*httpGetter, error) {
+	h := &httpGetter{
+		baseURL: peer + p.basePath,
+		peer:    peer,
+		pool:    p,
+	}
+	if p.opts.Breaker != nil {
+		h.breaker = newCircuitBreaker(*p.opts.Breaker)
+	}
+	return h, nil
+}
+
+// track 记录发往 peer 的进行中请求数，用于有界负载模式
+func (p *HTTPPool) track(peer string, delta int) {
+	if p.opts.LoadFactor <= 0 {
+		return
+	}
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.peers = consistenthash.New(defaultReplicas, nil)
-	p.peers.Add(peers...)
-	p.httpGetters = make(map[string]*httpGetter, len(peers))
-	for _, peer := range peers {
-		p.httpGetters[peer] = &httpGetter{
-			baseURL: peer + p.basePath,
-		}
+	bp, ok := p.placement.(consistenthash.BoundedPlacement)
+	if !ok {
+		return
 	}
+	if delta > 0 {
+		bp.Inc(peer)
+	} else {
+		bp.Done(peer)
+	}
+}
+
+// AddPeer 增量添加节点，已存在的节点会被忽略，其余节点的 key 分配保持不变
+func (p *HTTPPool) AddPeer(peers ...string) {
+	_ = p.addPeers(peers...)
+}
+
+// RemovePeer 增量删除节点，只有被删除节点上的 key 会迁移到其他节点
+func (p *HTTPPool) RemovePeer(peers ...string) {
+	p.removePeers(peers...)
 }
 
-// PickPeer 包装了一致性哈希算法的 Get() 方法，根据具体的 key，选择节点，返回节点对应的 HTTP 客户端
-func (p *HTTPPool) PickPeer(key string) (PeerGetter, bool) {
+// PeerStats 远程节点的状态
+type PeerStats struct {
+	Healthy  bool         // 是否参与节点选择
+	Breaker  BreakerState // 熔断器状态，未启用熔断器时为 BreakerClosed
+	Trips    int64        // 熔断器打开的总次数
+	Rejected int64        // 被熔断器拒绝的请求总数
+}
+
+// PeerStats 返回除自身外所有节点的状态
+func (p *HTTPPool) PeerStats() map[string]PeerStats {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if peer := p.peers.Get(key); peer != p.self && peer != "" {
-		p.Log("Pick peer %s", peer)
-		return p.httpGetters[peer], true
+	stats := make(map[string]PeerStats, len(p.getters))
+	for peer, getter := range p.getters {
+		if peer == p.self {
+			continue
+		}
+		s := PeerStats{Healthy: p.health[peer] == nil || !p.health[peer].ejected}
+		if getter.breaker != nil {
+			s.Breaker, s.Trips, s.Rejected = getter.breaker.snapshot()
+		}
+		stats[peer] = s
 	}
-	return nil, false
+	return stats
 }
 
 // 静态类型检查
-var _ PeerPicker = (*HTTPPool)(nil)
+var (
+	_ PeerPicker    = (*HTTPPool)(nil)
+	_ PeerLister    = (*HTTPPool)(nil)
+	_ ReplicaPicker = (*HTTPPool)(nil)
+)
 
 type httpGetter struct {
 	baseURL string
+	peer    string          // 节点地址，用于向 pool 报告负载
+	pool    *HTTPPool       // 所属的 HTTPPool，可以为 nil
+	breaker *circuitBreaker // 熔断器，未启用时为 nil
 }
 
-// Get 实现了 PeerGetter 接口的 Get() 方法
-func (h *httpGetter) Get(in *pb.Request, out *pb.Response) (err error) {
-	u := fmt.Sprintf(
+// PeerName 实现了 PeerNamer 接口，返回节点地址
+func (h *httpGetter) PeerName() string {
+	return h.peer
+}
+
+// errRequestTimeout 请求超过 HTTPPoolOptions.Timeout 时作为 context 的取消原因
+var errRequestTimeout = errors.New("zcache: peer request timed out")
+
+// withTimeout 为一次请求设置超时，cancel 需要在读取完响应体后调用
+func (h *httpGetter) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if h.pool == nil || h.pool.opts.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, h.pool.opts.Timeout, errRequestTimeout)
+}
+
+// do 发送请求，检查响应后调用 read 读取响应体，并向所属的 HTTPPool 报告进行中的请求数
+// 读取完响应体后才向熔断器和健康检查报告结果，发送完响应头后卡住的节点同样计为失败
+func (h *httpGetter) do(req *http.Request, read func(res *http.Response) error) error {
+	if h.pool == nil {
+		return roundTrip(http.DefaultClient, req, read)
+	}
+	if h.breaker != nil {
+		if err := h.breaker.allow(); err != nil {
+			return err
+		}
+	}
+	h.pool.track(h.peer, 1)
+	defer h.pool.track(h.peer, -1)
+	err := roundTrip(h.pool.client, req, read)
+	// 请求方主动取消不代表节点不健康，超时除外
+	if ctx := req.Context(); ctx.Err() != nil && context.Cause(ctx) != errRequestTimeout {
+		if h.breaker != nil {
+			h.breaker.cancel()
+		}
+		return err
+	}
+	// 502、503、504 与连接失败一样说明节点暂时不可用，其他状态码说明节点正常处理了请求
+	failure := err
+	var se *statusError
+	if errors.As(err, &se) && !unavailable(se.code) {
+		failure = nil
+	}
+	h.pool.reportHealth(h.peer, failure)
+	if h.breaker != nil {
+		h.breaker.record(failure != nil)
+	}
+	return err
+}
+
+// roundTrip 发送请求，检查响应状态后调用 read 读取响应体，read 可以为 nil
+func roundTrip(client *http.Client, req *http.Request, read func(res *http.Response) error) (err error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPeerUnavailable, err)
+	}
+	defer func() {
+		if cerr := res.Body.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("%w: closing response body: %w", ErrPeerUnavailable, cerr)
+		}
+	}()
+	if err = checkResponse(res); err != nil || read == nil {
+		return err
+	}
+	return read(res)
+}
+
+// readBody 读取 proto 编码的响应体，读取或解码失败说明节点暂时不可用
+func readBody(res *http.Response, out proto.Message) error {
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("%w: reading response body: %w", ErrPeerUnavailable, err)
+	}
+	if err = proto.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%w: decoding response body: %w", ErrPeerUnavailable, err)
+	}
+	return nil
+}
+
+// unavailable 判断响应状态码是否说明节点暂时不可用
+// 500 通常是数据源返回的错误，不计入节点的失败次数
+func unavailable(code int) bool {
+	return code == http.StatusBadGateway ||
+		code == http.StatusServiceUnavailable ||
+		code == http.StatusGatewayTimeout
+}
+
+// keyURL 拼接 group 和 key 对应的请求地址
+func (h *httpGetter) keyURL(group, key string) string {
+	return fmt.Sprintf(
 		"%v/%v/%v",
 		h.baseURL,
-		url.QueryEscape(in.GetGroup()),
-		url.QueryEscape(in.GetKey()),
+		url.PathEscape(group),
+		url.PathEscape(key),
 	)
-	res, err := http.Get(u)
+}
+
+// newRequest 创建带有协议版本和追踪上下文的请求，所属的 HTTPPool 配置了 Secret 时同时签名
+func (h *httpGetter) newRequest(ctx context.Context, method, u string, body []byte) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
 	if err != nil {
-		return
+		return nil, err
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			panic("res.Body.Close() fault!")
-		}
-	}(res.Body)
+	req.Header.Set(protocolHeader, protocolVersion)
+	req.Header.Set(forwardedHeader, "1")
+	req.Header.Set("Accept", contentTypeProto)
+	if body != nil {
+		req.Header.Set("Content-Type", contentTypeProto)
+	}
+	if h.pool != nil {
+		h.pool.opts.Tracer.Inject(ctx, req.Header)
+		h.pool.SignRequest(req, body)
+	}
+	return req, nil
+}
 
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("server returned: %v", res.Status)
+// statusError 远程节点返回了非 2xx 的状态码
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("server returned: %v", e.status)
+}
+
+// Is 将状态码映射为 ErrBadRequest、ErrNoSuchGroup、ErrUnauthorized 和 ErrPeerUnavailable
+func (e *statusError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.code == http.StatusBadRequest
+	case ErrNoSuchGroup:
+		return e.code == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.code == http.StatusUnauthorized
+	case ErrPeerUnavailable:
+		return unavailable(e.code)
+	}
+	return false
+}
+
+// checkResponse 检查响应状态和协议版本
+func checkResponse(res *http.Response) error {
+	if res.StatusCode/100 != 2 {
+		return &statusError{code: res.StatusCode, status: res.Status}
+	}
+	if v := res.Header.Get(protocolHeader); v != protocolVersion {
+		return fmt.Errorf("unsupported protocol version: %q", v)
 	}
+	return nil
+}
+
+// Get 实现了 PeerGetter 接口的 Get() 方法
+func (h *httpGetter) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	u := h.keyURL(in.GetGroup(), in.GetKey())
+	req, err := h.newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	return h.do(req, func(res *http.Response) error {
+		if ct := res.Header.Get("Content-Type"); ct != contentTypeProto {
+			return fmt.Errorf("unexpected content type: %q", ct)
+		}
+		return readBody(res, out)
+	})
+}
 
-	bytes, err := io.ReadAll(res.Body)
+// GetMulti 实现了 PeerBatchGetter 接口，一次请求获取多个 key
+func (h *httpGetter) GetMulti(ctx context.Context, in *pb.MultiRequest, out *pb.MultiResponse) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	u := h.keyURL(in.GetGroup(), "")
+	req, err := h.newRequest(ctx, http.MethodPost, u, body)
 	if err != nil {
-		return fmt.Errorf("reading response body: %v", err)
+		return err
 	}
+	return h.do(req, func(res *http.Response) error {
+		return readBody(res, out)
+	})
+}
 
-	if err = proto.Unmarshal(bytes, out); err != nil {
-		return fmt.Errorf("decoding response body: %v", err)
+// Set 实现了 PeerUpdater 接口，将缓存值写入远程节点
+func (h *httpGetter) Set(ctx context.Context, in *pb.SetRequest) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return err
 	}
+	return h.send(ctx, http.MethodPut, h.keyURL(in.GetGroup(), in.GetKey()), body)
+}
 
-	return
+// Remove 实现了 PeerUpdater 接口，删除远程节点上的缓存值
+func (h *httpGetter) Remove(ctx context.Context, in *pb.RemoveRequest) error {
+	return h.send(ctx, http.MethodDelete, h.keyURL(in.GetGroup(), in.GetKey()), nil)
+}
+
+// send 发送不需要响应体的请求
+func (h *httpGetter) send(ctx context.Context, method, u string, body []byte) error {
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	req, err := h.newRequest(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	return h.do(req, nil)
 }
 
 // 静态类型检查
-var _ PeerGetter = (*httpGetter)(nil)
+var (
+	_ PeerGetter      = (*httpGetter)(nil)
+	_ PeerUpdater     = (*httpGetter)(nil)
+	_ PeerBatchGetter = (*httpGetter)(nil)
+	_ PeerNamer       = (*httpGetter)(nil)
+)
@@ -0,0 +1,537 @@
+package zcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"zcache/consistenthash"
+	pb "zcache/zcachepb"
+)
+
+func TestHTTPSetRemove(t *testing.T) {
+	loads := 0
+	z := NewGroup("http-update", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte("origin"), nil
+		}))
+	pool := NewHTTPPool("self")
+	srv := httptest.NewServer(pool)
+	defer srv.Close()
+
+	peer := &httpGetter{baseURL: srv.URL + defaultBasePath}
+	ctx := context.Background()
+	if err := peer.Set(ctx, &pb.SetRequest{Group: z.name, Key: "k", Value: []byte("pushed")}); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := z.mainCache.get("k"); !ok || v.String() != "pushed" {
+		t.Fatalf("PUT should populate the cache, got %q, %v", v.String(), ok)
+	}
+
+	if err := peer.Remove(ctx, &pb.RemoveRequest{Group: z.name, Key: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := z.mainCache.get("k"); ok {
+		t.Fatalf("DELETE should remove the key")
+	}
+
+	if err := peer.Remove(ctx, &pb.RemoveRequest{Group: "no-such-group", Key: "k"}); err == nil {
+		t.Fatalf("expect error for unknown group")
+	}
+	if loads != 0 {
+		t.Fatalf("updates should not load from origin, loads=%d", loads)
+	}
+}
+
+func TestHTTPBodyLimit(t *testing.T) {
+	z := NewGroup("http-body-limit", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	pool := NewHTTPPool("self")
+	// 未配置 Secret 时同样限制请求体大小
+	for _, method := range []string{http.MethodPut, http.MethodPost} {
+		r := httptest.NewRequest(method, defaultBasePath+"/"+z.name+"/k", io.LimitReader(zeros{}, maxRequestBodyBytes+1))
+		w := httptest.NewRecorder()
+		pool.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "too large") {
+			t.Fatalf("%s: expect 400 for an oversized body, got %d %q", method, w.Code, w.Body.String())
+		}
+	}
+	if _, ok := z.mainCache.get("k"); ok {
+		t.Fatalf("oversized PUT should not populate the cache")
+	}
+}
+
+func TestHTTPResponseMeta(t *testing.T) {
+	NewGroup("http-meta", 2<<10, MetaGetterFunc(
+		func(_ context.Context, key string) ([]byte, Meta, error) {
+			return []byte(key), Meta{Version: "v1", TTL: time.Minute, NoStore: key == "nostore"}, nil
+		}))
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+	peer := &httpGetter{baseURL: srv.URL + defaultBasePath}
+
+	for _, key := range []string{"k", "k"} {
+		out := &pb.Response{}
+		if err := peer.Get(context.Background(), &pb.Request{Group: "http-meta", Key: key}, out); err != nil {
+			t.Fatal(err)
+		}
+		if m := out.GetMeta(); m.GetVersion() != "v1" || m.GetTtlMs() <= 0 || m.GetTtlMs() > 60000 || m.GetNoStore() {
+			t.Fatalf("expect version and remaining TTL from the owner, got %v", m)
+		}
+	}
+	out := &pb.Response{}
+	if err := peer.Get(context.Background(), &pb.Request{Group: "http-meta", Key: "nostore"}, out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.GetMeta().GetNoStore() {
+		t.Fatalf("expect no_store from the owner")
+	}
+}
+
+func TestHTTPGetMulti(t *testing.T) {
+	z := NewGroup("http-multi", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			if key == "missing" {
+				return nil, fmt.Errorf("%s not found", key)
+			}
+			return []byte("v-" + key), nil
+		}))
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+
+	peer := &httpGetter{baseURL: srv.URL + defaultBasePath}
+	out := &pb.MultiResponse{}
+	in := &pb.MultiRequest{Group: z.name, Keys: []string{"k1", "k2", "missing"}}
+	if err := peer.GetMulti(context.Background(), in, out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.GetValues()) != 2 || string(out.GetValues()["k1"]) != "v-k1" || string(out.GetValues()["k2"]) != "v-k2" {
+		t.Fatalf("unexpected batch response %v", out.GetValues())
+	}
+}
+
+// startPools 启动两个互为对端的 HTTPPool
+func startPools(t *testing.T) (*HTTPPool, *HTTPPool) {
+	var a, b *HTTPPool
+	srvA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.ServeHTTP(w, r)
+	}))
+	srvB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srvA.Close)
+	t.Cleanup(srvB.Close)
+	a, b = NewHTTPPool(srvA.URL), NewHTTPPool(srvB.URL)
+	a.Set(srvA.URL, srvB.URL)
+	b.Set(srvA.URL, srvB.URL)
+	return a, b
+}
+
+func TestHTTPPoolInterop(t *testing.T) {
+	NewGroup("interop", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value of " + key), nil
+		}))
+	a, b := startPools(t)
+
+	remote := 0
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key/%d with space", i)
+		for _, pool := range []*HTTPPool{a, b} {
+			peer, ok := pool.PickPeer(key)
+			if !ok {
+				continue
+			}
+			remote++
+			out := &pb.Response{}
+			if err := peer.Get(context.Background(), &pb.Request{Group: "interop", Key: key}, out); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(out.GetValue()); got != "value of "+key {
+				t.Fatalf("expect %q, got %q", "value of "+key, got)
+			}
+		}
+	}
+	if remote != 20 {
+		t.Fatalf("each key should be remote for exactly one pool, got %d", remote)
+	}
+}
+
+func TestHTTPResponseFormat(t *testing.T) {
+	NewGroup("format", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("raw-" + key), nil
+		}))
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+	u := srv.URL + defaultBasePath + "/format/k"
+
+	get := func(header http.Header) (*http.Response, []byte) {
+		req, _ := http.NewRequest(http.MethodGet, u, nil)
+		req.Header = header
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, body
+	}
+
+	// 默认返回 proto 编码的 Response
+	res, body := get(http.Header{})
+	out := &pb.Response{}
+	if err := proto.Unmarshal(body, out); err != nil || string(out.GetValue()) != "raw-k" {
+		t.Fatalf("expect protobuf response, got %q, %v", body, err)
+	}
+	if res.Header.Get("Content-Type") != contentTypeProto || res.Header.Get(protocolHeader) != protocolVersion {
+		t.Fatalf("unexpected headers %v", res.Header)
+	}
+
+	// Accept: application/octet-stream 返回原始值
+	res, body = get(http.Header{"Accept": {"text/html, application/octet-stream;q=0.9"}})
+	if string(body) != "raw-k" || res.Header.Get("Content-Type") != contentTypeRaw {
+		t.Fatalf("expect raw body, got %q (%s)", body, res.Header.Get("Content-Type"))
+	}
+
+	// 不支持的协议版本
+	res, _ = get(http.Header{protocolHeader: {"99"}})
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expect 400 for unknown protocol version, got %d", res.StatusCode)
+	}
+}
+
+func TestHTTPPoolAddRemovePeer(t *testing.T) {
+	peers := []string{"http://a", "http://b", "http://c", "http://d"}
+	pool := NewHTTPPool("http://a")
+	pool.AddPeer(peers...)
+	owner := func(key string) string {
+		peer, ok := pool.PickPeer(key)
+		if !ok {
+			return pool.self
+		}
+		return strings.TrimSuffix(peer.(*httpGetter).baseURL, defaultBasePath)
+	}
+
+	const samples = 10000
+	before := make([]string, samples)
+	for i := range before {
+		before[i] = owner(fmt.Sprintf("key%d", i))
+	}
+
+	// 并发的 PickPeer 不受节点变化影响
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < samples; i++ {
+			pool.PickPeer(fmt.Sprintf("key%d", i))
+		}
+	}()
+	pool.AddPeer("http://e")
+	pool.AddPeer("http://e")
+	<-done
+
+	moved := 0
+	for i, o := range before {
+		if now := owner(fmt.Sprintf("key%d", i)); now != o {
+			if now != "http://e" {
+				t.Fatalf("key%d moved from %s to %s", i, o, now)
+			}
+			moved++
+		}
+	}
+	if moved == 0 || moved > 2*samples/len(peers) {
+		t.Fatalf("adding a peer moved %d of %d keys", moved, samples)
+	}
+
+	pool.RemovePeer("http://e")
+	for i, o := range before {
+		if now := owner(fmt.Sprintf("key%d", i)); now != o {
+			t.Fatalf("key%d should return to %s, got %s", i, o, now)
+		}
+	}
+	if len(pool.Peers()) != len(peers)-1 {
+		t.Fatalf("expect %d remote peers, got %d", len(peers)-1, len(pool.Peers()))
+	}
+}
+
+func TestHTTPPoolSetWeighted(t *testing.T) {
+	pool := NewHTTPPool("http://self")
+	pool.SetWeighted(map[string]int{"http://self": 1, "http://big": 3})
+	remote := 0
+	const samples = 10000
+	for i := 0; i < samples; i++ {
+		if _, ok := pool.PickPeer(fmt.Sprintf("key%d", i)); ok {
+			remote++
+		}
+	}
+	if share := float64(remote) / samples; share < 0.65 || share > 0.85 {
+		t.Fatalf("peer with weight 3 of 4 got %.2f%% keys", share*100)
+	}
+}
+
+func TestHTTPPoolBoundedLoad(t *testing.T) {
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{LoadFactor: 0.25})
+	pool.Set("http://self", "http://busy", "http://idle")
+
+	var key string
+	for i := 0; ; i++ {
+		key = fmt.Sprintf("key%d", i)
+		if peer, ok := pool.PickPeer(key); ok && peer.(*httpGetter).peer == "http://busy" {
+			break
+		}
+	}
+	// busy 上有 4 个进行中的请求，超过 (1+0.25) * 5/3 的上限
+	for i := 0; i < 4; i++ {
+		pool.track("http://busy", 1)
+	}
+	if peer, ok := pool.PickPeer(key); ok && peer.(*httpGetter).peer == "http://busy" {
+		t.Fatalf("overloaded peer should be skipped")
+	}
+	for i := 0; i < 4; i++ {
+		pool.track("http://busy", -1)
+	}
+	if peer, ok := pool.PickPeer(key); !ok || peer.(*httpGetter).peer != "http://busy" {
+		t.Fatalf("key should return to its owner once the load drops")
+	}
+}
+
+func TestHTTPForwardedLoadsLocally(t *testing.T) {
+	var loads atomic.Int32
+	z := NewGroup("http-forwarded", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads.Add(1)
+			return []byte(key), nil
+		}))
+	srv := httptest.NewServer(NewHTTPPool("remote"))
+	defer srv.Close()
+	// 同一进程中两个节点共用一个 Group，收到请求的节点按 pool 的选择仍会认为 key 属于远程节点，
+	// 模拟有界负载模式下各节点选择不一致的情况
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{Timeout: time.Second})
+	pool.Set("http://self", srv.URL)
+	z.RegisterPeers(pool)
+	key, peer := remoteKey(t, pool)
+
+	out := &pb.Response{}
+	if err := peer.Get(context.Background(), &pb.Request{Group: z.name, Key: key}, out); err != nil || string(out.Value) != key {
+		t.Fatalf("Get = %q, %v", out.Value, err)
+	}
+	if n, s := loads.Load(), z.Stats(); n != 1 || s.ServerRequests != 1 || s.PeerLoads+s.PeerErrors != 0 {
+		t.Fatalf("forwarded request should be loaded by the receiver without another hop, loads=%d stats=%+v", n, s)
+	}
+}
+
+func TestHTTPPoolPlacement(t *testing.T) {
+	for name, placement := range map[string]func() consistenthash.Placement{
+		"rendezvous": func() consistenthash.Placement { return consistenthash.NewRendezvous(nil) },
+		"jump":       func() consistenthash.Placement { return consistenthash.NewJump(nil) },
+		"maglev":     func() consistenthash.Placement { return consistenthash.NewMaglev(0, nil) },
+	} {
+		pool := NewHTTPPoolOpts("http://a", &HTTPPoolOptions{Placement: placement})
+		pool.SetWeighted(map[string]int{"http://a": 1, "http://b": 2})
+		pool.AddPeer("http://c")
+		remote := 0
+		for i := 0; i < 3000; i++ {
+			if _, ok := pool.PickPeer(fmt.Sprintf("key%d", i)); ok {
+				remote++
+			}
+		}
+		// 不支持权重的策略平均分配，a 分到约 1/3 的 key
+		if remote < 1800 || remote > 2200 {
+			t.Errorf("%s: expect about 2/3 remote keys, got %d of 3000", name, remote)
+		}
+	}
+}
+
+func TestHTTPPoolConsistentOwners(t *testing.T) {
+	peers := []string{"http://a", "http://b", "http://c", "http://d", "http://e"}
+	for name, placement := range map[string]func() consistenthash.Placement{
+		"ring":       nil,
+		"rendezvous": func() consistenthash.Placement { return consistenthash.NewRendezvous(nil) },
+		"jump":       func() consistenthash.Placement { return consistenthash.NewJump(nil) },
+		"maglev":     func() consistenthash.Placement { return consistenthash.NewMaglev(0, nil) },
+	} {
+		opts := &HTTPPoolOptions{Placement: placement, HealthCheckInterval: time.Hour, FailureThreshold: 1}
+		a := NewHTTPPoolOpts("http://a", opts)
+		a.Set(peers...)
+		b := NewHTTPPoolOpts("http://a", opts)
+		for i := len(peers) - 1; i >= 0; i-- {
+			b.AddPeer(peers[i])
+		}
+		// 节点被移出后重新加入，不应改变 key 的分配
+		b.reportHealth("http://c", errors.New("down"))
+		b.reportHealth("http://c", nil)
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("key%d", i)
+			if oa, ob := a.ring(key).Owner, b.ring(key).Owner; oa != ob {
+				t.Fatalf("%s: %s owned by %s and %s on pools with the same peers", name, key, oa, ob)
+			}
+		}
+		a.StopHealthCheck()
+		b.StopHealthCheck()
+	}
+}
+
+func TestHTTPPoolPickPeers(t *testing.T) {
+	pool := NewHTTPPool("http://a")
+	pool.Set("http://a", "http://b", "http://c")
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		peers := pool.PickPeers(key, 3)
+		if len(peers) != 3 {
+			t.Fatalf("expect 3 replicas, got %d", len(peers))
+		}
+		self := 0
+		seen := make(map[PeerGetter]bool)
+		for _, peer := range peers {
+			if peer == nil {
+				self++
+				continue
+			}
+			seen[peer] = true
+		}
+		if self != 1 || len(seen) != 2 {
+			t.Fatalf("replicas should be distinct and include self once, got %v", peers)
+		}
+		first, ok := pool.PickPeer(key)
+		if (ok && peers[0] != first) || (!ok && peers[0] != nil) {
+			t.Fatalf("first replica should match PickPeer")
+		}
+	}
+}
+
+func TestHTTPPoolTimeout(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{
+		Timeout:             20 * time.Millisecond,
+		HealthCheckInterval: time.Hour,
+		FailureThreshold:    1,
+	})
+	defer pool.StopHealthCheck()
+	pool.Set("http://self", srv.URL)
+	key, peer := remoteKey(t, pool)
+
+	start := time.Now()
+	err := peer.Get(context.Background(), &pb.Request{Group: "g", Key: key}, &pb.Response{})
+	if err == nil {
+		t.Fatalf("expect error from a hanging peer")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("request should time out quickly, took %v", d)
+	}
+	if pool.Healthy(srv.URL) {
+		t.Fatalf("timed out request should count as a failure")
+	}
+}
+
+func TestHTTPPoolCallerCancel(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{
+		Timeout:             time.Hour,
+		HealthCheckInterval: time.Hour,
+		FailureThreshold:    1,
+	})
+	defer pool.StopHealthCheck()
+	pool.Set("http://self", srv.URL)
+	key, peer := remoteKey(t, pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := peer.Get(ctx, &pb.Request{Group: "g", Key: key}, &pb.Response{}); err == nil {
+		t.Fatalf("expect error after the caller gives up")
+	}
+	if !pool.Healthy(srv.URL) {
+		t.Fatalf("caller cancellation should not count as a failure")
+	}
+}
+
+// countingTransport 记录经过的请求数
+type countingTransport struct {
+	n atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.n.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestHTTPPoolClient(t *testing.T) {
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	defer srv.Close()
+
+	rt := &countingTransport{}
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{Transport: rt})
+	pool.Set("http://self", srv.URL)
+	key, peer := remoteKey(t, pool)
+	_ = peer.Get(context.Background(), &pb.Request{Group: "no-such-group", Key: key}, &pb.Response{})
+	if rt.n.Load() != 1 {
+		t.Fatalf("expect requests to go through the injected transport, got %d", rt.n.Load())
+	}
+
+	client := &http.Client{}
+	if p := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{Client: client, Transport: rt}); p.client != client {
+		t.Fatalf("Client should take precedence over Transport")
+	}
+	p := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{MaxIdleConnsPerHost: 64})
+	if n := p.client.Transport.(*http.Transport).MaxIdleConnsPerHost; n != 64 {
+		t.Fatalf("expect MaxIdleConnsPerHost 64, got %d", n)
+	}
+}
+
+func TestHTTPPoolH2C(t *testing.T) {
+	z := NewGroup("http-h2c", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v-" + key), nil
+		}))
+	server := NewHTTPPoolOpts("self", &HTTPPoolOptions{H2C: true})
+	var major atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		major.Store(int32(r.ProtoMajor))
+		server.ServeHTTP(w, r)
+	}))
+	srv.Config.Protocols = server.Protocols()
+	srv.Start()
+	defer srv.Close()
+
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{H2C: true})
+	pool.Set("http://self", srv.URL)
+	key, peer := remoteKey(t, pool)
+	out := &pb.Response{}
+	if err := peer.Get(context.Background(), &pb.Request{Group: z.name, Key: key}, out); err != nil {
+		t.Fatal(err)
+	}
+	if string(out.GetValue()) != "v-"+key {
+		t.Fatalf("unexpected value %q", out.GetValue())
+	}
+	if major.Load() != 2 {
+		t.Fatalf("expect HTTP/2 between peers, got HTTP/%d", major.Load())
+	}
+}
@@ -0,0 +1,88 @@
+package zcache
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	pb "zcache/zcachepb"
+)
+
+// newTestLogger 返回写入 buf 的 slog.Logger
+func newTestLogger(buf *bytes.Buffer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: level}))
+}
+
+func TestGroupLogging(t *testing.T) {
+	var buf bytes.Buffer
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	quiet := NewGroup("log-quiet", 2<<10, getter, WithLogger(newTestLogger(&buf, slog.LevelDebug)))
+	_, _ = quiet.Get("k")
+	_, _ = quiet.Get("k")
+	if buf.Len() != 0 {
+		t.Fatalf("cache hits should not be logged by default, got %q", buf.String())
+	}
+
+	verbose := NewGroup("log-verbose", 2<<10, getter,
+		WithLogger(newTestLogger(&buf, slog.LevelDebug)), WithRequestLogging())
+	_, _ = verbose.Get("k")
+	_, _ = verbose.Get("k")
+	if out := buf.String(); !strings.Contains(out, "level=DEBUG") || !strings.Contains(out, "group=log-verbose") || !strings.Contains(out, "key=k") {
+		t.Fatalf("expect a debug line for the cache hit, got %q", out)
+	}
+
+	// 日志级别由 Handler 决定
+	buf.Reset()
+	filtered := NewGroup("log-filtered", 2<<10, getter,
+		WithLogger(newTestLogger(&buf, slog.LevelInfo)), WithRequestLogging())
+	_, _ = filtered.Get("k")
+	_, _ = filtered.Get("k")
+	if buf.Len() != 0 {
+		t.Fatalf("debug lines should be dropped at info level, got %q", buf.String())
+	}
+}
+
+func TestHTTPPoolLogging(t *testing.T) {
+	NewGroup("log-http", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	for _, logRequests := range []bool{false, true} {
+		var buf bytes.Buffer
+		pool := NewHTTPPoolOpts("self", &HTTPPoolOptions{
+			Logger:      newTestLogger(&buf, slog.LevelDebug),
+			LogRequests: logRequests,
+		})
+		srv := httptest.NewServer(pool)
+		peer := &httpGetter{baseURL: srv.URL + defaultBasePath}
+		err := peer.Get(context.Background(), &pb.Request{Group: "log-http", Key: "k"}, &pb.Response{})
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		logged := strings.Contains(buf.String(), "serve request")
+		if logged != logRequests {
+			t.Fatalf("LogRequests=%v, got log %q", logRequests, buf.String())
+		}
+	}
+}
+
+func TestRecoverLogging(t *testing.T) {
+	var buf bytes.Buffer
+	pool := NewHTTPPoolOpts("self", &HTTPPoolOptions{Logger: newTestLogger(&buf, slog.LevelInfo)})
+	NewGroup("log-panic", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		panic("getter bug")
+	}))
+	rec := httptest.NewRecorder()
+	pool.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, defaultBasePath+"/log-panic/k", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expect 500, got %d", rec.Code)
+	}
+	if out := buf.String(); !strings.Contains(out, "level=ERROR") || !strings.Contains(out, "getter bug") {
+		t.Fatalf("expect the panic to be logged, got %q", out)
+	}
+}
@@ -2,6 +2,7 @@ package lru
 
 import (
 	"container/list"
+	"time"
 )
 
 type Value interface {
@@ -10,17 +11,21 @@ type Value interface {
 
 // Cache LRU 缓存
 type Cache struct {
-	maxBytes  int64                    // 允许使用的最大内存
-	nbytes    int64                    // 当前已使用的内存
-	cache     map[string]*list.Element // 键是字符串，值是双向链表中对应节点的指针
-	list      *list.List               // Go 语言标准库实现的双向链表
-	OnEvicted func(k string, v Value)  // 某条记录被移除时的回调函数，可以为 nil
+	maxBytes      int64                    // 允许使用的最大内存，为 0 时不限制
+	nbytes        int64                    // 当前已使用的内存
+	cache         map[string]*list.Element // 键是字符串，值是双向链表中对应节点的指针
+	list          *list.List               // Go 语言标准库实现的双向链表
+	OnEvicted     func(k string, v Value)  // 某条记录被移除时的回调函数，可以为 nil
+	EntryOverhead int64                    // 每条记录额外计入的内存开销，修改后只影响之后添加或更新的记录
+	Now           func() time.Time         // 判断过期使用的时钟，为 nil 时使用 time.Now
 }
 
 // entry kv 数据载体
 type entry struct {
-	key   string // 钦定为 string 类型，方便查找
-	value Value  // 需要实现 Len 函数获取数据大小
+	key    string    // 钦定为 string 类型，方便查找
+	value  Value     // 需要实现 Len 函数获取数据大小
+	expire time.Time // 过期时间，零值表示永不过期
+	size   int64     // 添加时计入的内存，删除时按同样的大小扣除
 }
 
 func New(maxBytes int64, onEvicted func(k string, v Value)) *Cache {
@@ -32,12 +37,28 @@ func New(maxBytes int64, onEvicted func(k string, v Value)) *Cache {
 	}
 }
 
+func (c *Cache) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// expired 判断记录在 now 时刻是否已经过期
+func (e *entry) expired(now time.Time) bool {
+	return !e.expire.IsZero() && !now.Before(e.expire)
+}
+
+// size 计算一条记录占用的内存：key + value + 固定开销
+func (c *Cache) size(key string, value Value) int64 {
+	return int64(len(key)) + int64(value.Len()) + c.EntryOverhead
+}
+
 // removeElement 删除元素
 func (c *Cache) removeElement(e *list.Element) {
 	c.list.Remove(e)
 	kv := e.Value.(*entry)
-	c.nbytes -= int64(len(kv.key))
-	c.nbytes -= int64(kv.value.Len())
+	c.nbytes -= kv.size
 	delete(c.cache, kv.key)
 	if c.OnEvicted != nil {
 		c.OnEvicted(kv.key, kv.value)
@@ -65,39 +86,91 @@ func (c *Cache) Remove(key string) {
 	}
 }
 
-// Add 添加或更新节点
+// Add 添加或更新一个永不过期的节点
 func (c *Cache) Add(k string, v Value) {
+	c.AddWithExpire(k, v, time.Time{})
+}
+
+// AddWithExpire 添加或更新节点，expire 为零值时永不过期
+// 超出 maxBytes 时从队尾开始淘汰，直到回到预算之内
+// 单条记录超过 maxBytes 时不写入，并删除 key 原有的值，其他记录不受影响
+func (c *Cache) AddWithExpire(k string, v Value, expire time.Time) {
 	if c.cache == nil {
+		c.cache = make(map[string]*list.Element)
+		c.list = list.New()
+	}
+	size := c.size(k, v)
+	if c.maxBytes != 0 && size > c.maxBytes {
+		c.Remove(k)
 		return
 	}
 	if e, hit := c.cache[k]; hit {
 		c.list.MoveToFront(e)
 		kv := e.Value.(*entry)
-		c.nbytes -= int64(kv.value.Len())
+		c.nbytes += size - kv.size
 		kv.value = v
-		c.nbytes += int64(kv.value.Len())
+		kv.expire = expire
+		kv.size = size
 	} else {
-		e = c.list.PushFront(&entry{k, v})
+		e = c.list.PushFront(&entry{key: k, value: v, expire: expire, size: size})
 		c.cache[k] = e
+		c.nbytes += size
 	}
-	for c.maxBytes != 0 && int64(c.list.Len()) > c.maxBytes {
+	for c.maxBytes != 0 && c.nbytes > c.maxBytes {
 		c.RemoveOldest()
 	}
 }
 
-// Get 查找指定的 key 对应的节点
+// Get 查找指定的 key 对应的节点，已过期的节点会被顺带删除
 func (c *Cache) Get(k string) (value Value, ok bool) {
 	if c.cache == nil {
 		return
 	}
 	if e, hit := c.cache[k]; hit {
+		kv := e.Value.(*entry)
+		if kv.expired(c.now()) {
+			c.removeElement(e)
+			return
+		}
 		c.list.MoveToFront(e)
+		return kv.value, true
+	}
+	return
+}
+
+// Peek 查找 key 对应的值，但不更新它在淘汰顺序中的位置，也不删除已过期的节点
+func (c *Cache) Peek(k string) (value Value, ok bool) {
+	if c.cache == nil {
+		return
+	}
+	if e, hit := c.cache[k]; hit {
 		kv := e.Value.(*entry)
+		if kv.expired(c.now()) {
+			return
+		}
 		return kv.value, true
 	}
 	return
 }
 
+// RemoveExpired 删除所有已过期的节点，返回删除的数量
+func (c *Cache) RemoveExpired() int {
+	if c.cache == nil {
+		return 0
+	}
+	now := c.now()
+	n := 0
+	for e := c.list.Back(); e != nil; {
+		prev := e.Prev()
+		if e.Value.(*entry).expired(now) {
+			c.removeElement(e)
+			n++
+		}
+		e = prev
+	}
+	return n
+}
+
 // Clear 清空缓存
 func (c *Cache) Clear() {
 	if c.OnEvicted != nil {
@@ -108,6 +181,7 @@ func (c *Cache) Clear() {
 	}
 	c.list = nil
 	c.cache = nil
+	c.nbytes = 0
 }
 
 // Len 返回缓存中节点的数量
@@ -117,3 +191,8 @@ func (c *Cache) Len() int {
 	}
 	return c.list.Len()
 }
+
+// Bytes 返回缓存当前占用的内存
+func (c *Cache) Bytes() int64 {
+	return c.nbytes
+}
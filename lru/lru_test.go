@@ -1,8 +1,12 @@
 package lru
 
 import (
+	"math/rand"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 type String string
@@ -63,3 +67,142 @@ func TestSet(t *testing.T) {
 		t.Fatal("expected 6 but got", lru.nbytes)
 	}
 }
+
+func TestEntryOverhead(t *testing.T) {
+	lru := New(int64(0), nil)
+	lru.EntryOverhead = 16
+	lru.Add("k1", String("v1"))
+	lru.Add("k2", String("value2"))
+	if want := int64(2+2+16) + int64(2+6+16); lru.Bytes() != want {
+		t.Fatalf("expected %d bytes but got %d", want, lru.Bytes())
+	}
+	lru.Remove("k1")
+	if want := int64(2 + 6 + 16); lru.Bytes() != want {
+		t.Fatalf("expected %d bytes but got %d", want, lru.Bytes())
+	}
+
+	// 修改后只影响之后写入的记录，删除时按写入时的大小扣除
+	lru.EntryOverhead = 32
+	lru.Add("k3", String("v3"))
+	lru.Remove("k2")
+	if want := int64(2 + 2 + 32); lru.Bytes() != want {
+		t.Fatalf("expected %d bytes but got %d", want, lru.Bytes())
+	}
+	lru.Remove("k3")
+	if lru.Bytes() != 0 {
+		t.Fatalf("expected 0 bytes but got %d", lru.Bytes())
+	}
+}
+
+func TestAddOversized(t *testing.T) {
+	var evicted []string
+	lru := New(int64(10), func(k string, _ Value) {
+		evicted = append(evicted, k)
+	})
+	lru.Add("k1", String("12"))
+	lru.Add("k2", String("12"))
+	lru.Add("big", String("12345678901"))
+	if _, ok := lru.Get("big"); ok || lru.Len() != 2 || len(evicted) != 0 {
+		t.Fatalf("oversized entry should be rejected without evicting others, len=%d evicted=%v", lru.Len(), evicted)
+	}
+	// 更新为超出预算的值时删除原有的值
+	lru.Add("k1", String("12345678901"))
+	if _, ok := lru.Get("k1"); ok || lru.Bytes() != 4 {
+		t.Fatalf("oversized update should drop the old value, bytes=%d", lru.Bytes())
+	}
+}
+
+func TestUpdateEvicts(t *testing.T) {
+	lru := New(int64(20), nil)
+	lru.Add("k1", String("1234"))
+	lru.Add("k2", String("1234"))
+	// 更新 k2 后总量超出预算，应淘汰最久未访问的 k1
+	lru.Add("k2", String("12345678901234"))
+	if _, ok := lru.Get("k1"); ok {
+		t.Fatalf("k1 should have been evicted")
+	}
+	if lru.Bytes() != int64(len("k2")+len("12345678901234")) {
+		t.Fatalf("unexpected bytes %d", lru.Bytes())
+	}
+}
+
+func TestClear(t *testing.T) {
+	lru := New(int64(0), nil)
+	lru.Add("k1", String("v1"))
+	lru.Clear()
+	if lru.Len() != 0 || lru.Bytes() != 0 {
+		t.Fatalf("Clear failed, len=%d bytes=%d", lru.Len(), lru.Bytes())
+	}
+	lru.Add("k2", String("v2"))
+	if _, ok := lru.Get("k2"); !ok {
+		t.Fatalf("Add after Clear failed")
+	}
+}
+
+func TestPeek(t *testing.T) {
+	k1, k2, v := "k1", "k2", "v"
+	lru := New(int64(len(k1+k2+v+v)), nil)
+	lru.Add(k1, String(v))
+	lru.Add(k2, String(v))
+	if got, ok := lru.Peek(k1); !ok || string(got.(String)) != v {
+		t.Fatalf("Peek k1 failed")
+	}
+	// Peek 不更新淘汰顺序，k1 仍然最先被淘汰
+	lru.Add("k3", String(v))
+	if _, ok := lru.Peek(k1); ok {
+		t.Fatalf("Peek should not move k1 to the front")
+	}
+}
+
+// 在混合大小的插入和更新下，已用内存始终等于各条记录之和且不超过预算
+func TestBytesInvariant(t *testing.T) {
+	const maxBytes = 256
+	lru := New(int64(maxBytes), nil)
+	lru.EntryOverhead = 8
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		k := "key" + strconv.Itoa(rnd.Intn(64))
+		v := strings.Repeat("x", rnd.Intn(64))
+		lru.Add(k, String(v))
+
+		var sum int64
+		for e := lru.list.Front(); e != nil; e = e.Next() {
+			kv := e.Value.(*entry)
+			sum += int64(len(kv.key)+kv.value.Len()) + lru.EntryOverhead
+		}
+		if sum != lru.Bytes() {
+			t.Fatalf("step %d: accounted %d bytes, actual %d", i, lru.Bytes(), sum)
+		}
+		if lru.Bytes() > maxBytes {
+			t.Fatalf("step %d: %d bytes exceeds budget %d", i, lru.Bytes(), maxBytes)
+		}
+		if len(lru.cache) != lru.list.Len() {
+			t.Fatalf("step %d: map has %d entries, list has %d", i, len(lru.cache), lru.list.Len())
+		}
+	}
+}
+
+func TestExpire(t *testing.T) {
+	now := time.Unix(0, 0)
+	lru := New(int64(0), nil)
+	lru.Now = func() time.Time { return now }
+	lru.AddWithExpire("k1", String("v1"), now.Add(time.Second))
+	lru.AddWithExpire("k2", String("v2"), now.Add(time.Minute))
+	lru.Add("k3", String("v3"))
+
+	now = now.Add(time.Second)
+	if _, ok := lru.Get("k1"); ok {
+		t.Fatalf("k1 should have expired")
+	}
+	if lru.Len() != 2 || lru.Bytes() != int64(len("k2v2k3v3")) {
+		t.Fatalf("expired entry not reclaimed on Get, len=%d bytes=%d", lru.Len(), lru.Bytes())
+	}
+
+	now = now.Add(time.Hour)
+	if n := lru.RemoveExpired(); n != 1 {
+		t.Fatalf("expected 1 expired entry but removed %d", n)
+	}
+	if _, ok := lru.Get("k3"); !ok {
+		t.Fatalf("k3 should never expire")
+	}
+}
@@ -0,0 +1,244 @@
+package zcache
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets 耗时直方图的上界，单位为秒，与 Prometheus 客户端的默认值相同
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// histogram 并发安全的累积直方图
+type histogram struct {
+	counts []atomic.Int64 // 落在每个桶内的次数，最后一个为 +Inf
+	sum    atomic.Uint64  // 总耗时的秒数，按 float64 位模式存储
+	count  atomic.Int64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]atomic.Int64, len(latencyBuckets)+1)}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	v := d.Seconds()
+	i, _ := slices.BinarySearch(latencyBuckets, v)
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// peerHistograms 按节点区分的耗时直方图
+type peerHistograms struct {
+	mu sync.Mutex
+	m  map[string]*histogram
+}
+
+func (p *peerHistograms) get(peer string) *histogram {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.m == nil {
+		p.m = make(map[string]*histogram)
+	}
+	h, ok := p.m[peer]
+	if !ok {
+		h = newHistogram()
+		p.m[peer] = h
+	}
+	return h
+}
+
+// snapshot 返回按节点地址排序的直方图
+func (p *peerHistograms) snapshot() ([]string, []*histogram) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	peers := make([]string, 0, len(p.m))
+	for peer := range p.m {
+		peers = append(peers, peer)
+	}
+	slices.Sort(peers)
+	hists := make([]*histogram, len(peers))
+	for i, peer := range peers {
+		hists[i] = p.m[peer]
+	}
+	return peers, hists
+}
+
+// MetricsHandler 返回以 Prometheus 文本格式导出所有 Group 指标的 handler，
+// 例如 http.Handle("/metrics", zcache.MetricsHandler(pool))
+// pool 不为 nil 时同时导出远程节点的健康和熔断器状态
+func MetricsHandler(pool *HTTPPool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		writeMetrics(bw, listGroups(), pool)
+		_ = bw.Flush()
+	})
+}
+
+// listGroups 返回按名称排序的全部 Group
+func listGroups() []*Group {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]*Group, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, g)
+	}
+	slices.SortFunc(list, func(a, b *Group) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return list
+}
+
+func writeMetrics(w *bufio.Writer, list []*Group, pool *HTTPPool) {
+	stats := make([]Stats, len(list))
+	for i, g := range list {
+		stats[i] = g.Stats()
+	}
+	counters := []struct {
+		name, help string
+		value      func(Stats) int64
+	}{
+		{"zcache_gets_total", "Keys requested through Get and GetMulti.", func(s Stats) int64 { return s.Gets }},
+		{"zcache_cache_hits_total", "Keys served from the main or hot cache.", func(s Stats) int64 { return s.CacheHits }},
+		{"zcache_cache_misses_total", "Keys not found in any local cache.", func(s Stats) int64 { return s.Loads }},
+		{"zcache_loads_deduped_total", "Loads actually executed after singleflight deduplication.", func(s Stats) int64 { return s.LoadsDeduped }},
+		{"zcache_peer_loads_total", "Successful loads from remote peers.", func(s Stats) int64 { return s.PeerLoads }},
+		{"zcache_peer_errors_total", "Failed loads from remote peers.", func(s Stats) int64 { return s.PeerErrors }},
+		{"zcache_origin_loads_total", "Successful loads from the origin getter.", func(s Stats) int64 { return s.LocalLoads }},
+		{"zcache_origin_errors_total", "Failed loads from the origin getter.", func(s Stats) int64 { return s.LocalLoadErrs }},
+		{"zcache_server_requests_total", "Get requests received from other peers.", func(s Stats) int64 { return s.ServerRequests }},
+	}
+	for _, c := range counters {
+		writeHeader(w, c.name, c.help, "counter")
+		for i, g := range list {
+			writeSample(w, c.name, labels("group", g.name), float64(c.value(stats[i])))
+		}
+	}
+
+	writeHeader(w, "zcache_inflight_loads", "Loads currently in progress.", "gauge")
+	for i, g := range list {
+		writeSample(w, "zcache_inflight_loads", labels("group", g.name), float64(stats[i].InFlight))
+	}
+
+	caches := []struct {
+		name  string
+		which CacheType
+	}{{"main", MainCache}, {"hot", HotCache}}
+	cacheStats := make([][]CacheStats, len(list))
+	for i, g := range list {
+		for _, c := range caches {
+			cacheStats[i] = append(cacheStats[i], g.CacheStats(c.which))
+		}
+	}
+	gauges := []struct {
+		name, help, typ string
+		value           func(CacheStats) int64
+	}{
+		{"zcache_cache_bytes", "Bytes held by the cache.", "gauge", func(s CacheStats) int64 { return s.Bytes }},
+		{"zcache_cache_items", "Entries held by the cache.", "gauge", func(s CacheStats) int64 { return s.Items }},
+		{"zcache_cache_evictions_total", "Entries evicted for capacity or expiry.", "counter", func(s CacheStats) int64 { return s.Evictions }},
+	}
+	for _, m := range gauges {
+		writeHeader(w, m.name, m.help, m.typ)
+		for i, g := range list {
+			for j, c := range caches {
+				writeSample(w, m.name, labels("group", g.name, "cache", c.name), float64(m.value(cacheStats[i][j])))
+			}
+		}
+	}
+
+	writeHeader(w, "zcache_origin_load_duration_seconds", "Latency of loads from the origin getter.", "histogram")
+	for _, g := range list {
+		writeHistogram(w, "zcache_origin_load_duration_seconds", labels("group", g.name), g.originLatency)
+	}
+	writeHeader(w, "zcache_peer_load_duration_seconds", "Latency of loads from remote peers.", "histogram")
+	for _, g := range list {
+		peers, hists := g.peerLatency.snapshot()
+		for i, peer := range peers {
+			writeHistogram(w, "zcache_peer_load_duration_seconds", labels("group", g.name, "peer", peer), hists[i])
+		}
+	}
+
+	if pool == nil {
+		return
+	}
+	peerStats := pool.PeerStats()
+	peers := make([]string, 0, len(peerStats))
+	for peer := range peerStats {
+		peers = append(peers, peer)
+	}
+	slices.Sort(peers)
+	peerMetrics := []struct {
+		name, help, typ string
+		value           func(PeerStats) float64
+	}{
+		{"zcache_peer_up", "Whether the peer takes part in placement (1) or is ejected (0).", "gauge", func(s PeerStats) float64 {
+			if s.Healthy {
+				return 1
+			}
+			return 0
+		}},
+		{"zcache_peer_breaker_state", "Circuit breaker state: 0 closed, 1 open, 2 half-open.", "gauge", func(s PeerStats) float64 { return float64(s.Breaker) }},
+		{"zcache_peer_breaker_trips_total", "Times the circuit breaker opened.", "counter", func(s PeerStats) float64 { return float64(s.Trips) }},
+		{"zcache_peer_breaker_rejected_total", "Requests rejected by the circuit breaker.", "counter", func(s PeerStats) float64 { return float64(s.Rejected) }},
+	}
+	for _, m := range peerMetrics {
+		writeHeader(w, m.name, m.help, m.typ)
+		for _, peer := range peers {
+			writeSample(w, m.name, labels("peer", peer), m.value(peerStats[peer]))
+		}
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	_, _ = fmt.Fprintf(w, "%s{%s} %s\n", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func writeHistogram(w *bufio.Writer, name, labels string, h *histogram) {
+	var cumulative int64
+	for i, le := range latencyBuckets {
+		cumulative += h.counts[i].Load()
+		bucket := labels + `,le="` + strconv.FormatFloat(le, 'g', -1, 64) + `"`
+		writeSample(w, name+"_bucket", bucket, float64(cumulative))
+	}
+	// count 在桶之后读取，保证 +Inf 桶不小于其他桶
+	count := max(h.count.Load(), cumulative)
+	writeSample(w, name+"_bucket", labels+`,le="+Inf"`, float64(count))
+	writeSample(w, name+"_sum", labels, math.Float64frombits(h.sum.Load()))
+	writeSample(w, name+"_count", labels, float64(count))
+}
+
+// labelEscaper 转义标签值中的反斜杠、双引号和换行
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels 将成对的标签名和值格式化为 a="x",b="y"
+func labels(kv ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(kv); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(kv[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(kv[i+1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
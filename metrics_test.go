@@ -0,0 +1,81 @@
+package zcache
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	z := NewGroup("metrics", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v"), nil
+		}))
+	peer := &fakePeer{values: map[string][]byte{"r": []byte("remote")}}
+	z.RegisterPeers(&fakePicker{peers: map[byte]*fakePeer{'r': peer}})
+	_, _ = z.Get("l")
+	_, _ = z.Get("l")
+	_, _ = z.Get("r")
+
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{Breaker: &BreakerOptions{FailureThreshold: 1}})
+	pool.Set("http://self", "http://peer")
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(pool).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE zcache_gets_total counter",
+		`zcache_gets_total{group="metrics"} 3`,
+		`zcache_cache_hits_total{group="metrics"} 1`,
+		`zcache_cache_misses_total{group="metrics"} 2`,
+		`zcache_origin_loads_total{group="metrics"} 1`,
+		`zcache_peer_loads_total{group="metrics"} 1`,
+		`zcache_inflight_loads{group="metrics"} 0`,
+		`zcache_cache_items{group="metrics",cache="main"} 1`,
+		"# TYPE zcache_origin_load_duration_seconds histogram",
+		`zcache_origin_load_duration_seconds_bucket{group="metrics",le="+Inf"} 1`,
+		`zcache_origin_load_duration_seconds_count{group="metrics"} 1`,
+		`zcache_peer_load_duration_seconds_count{group="metrics",peer="unknown"} 1`,
+		`zcache_peer_up{peer="http://peer"} 1`,
+		`zcache_peer_breaker_state{peer="http://peer"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram()
+	h.observe(time.Millisecond)
+	h.observe(10 * time.Millisecond)
+	h.observe(time.Minute)
+	var b strings.Builder
+	w := bufio.NewWriter(&b)
+	writeHistogram(w, "h", labels("group", "g"), h)
+	_ = w.Flush()
+	for _, line := range []string{
+		`h_bucket{group="g",le="0.005"} 1`,
+		`h_bucket{group="g",le="0.01"} 2`,
+		`h_bucket{group="g",le="10"} 2`,
+		`h_bucket{group="g",le="+Inf"} 3`,
+		`h_sum{group="g"} 60.011`,
+		`h_count{group="g"} 3`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	if got := labels("group", `a"b\c`+"\n"); got != `group="a\"b\\c\n"` {
+		t.Fatalf("unexpected escaping %s", got)
+	}
+}
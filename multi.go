@@ -0,0 +1,243 @@
+package zcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"zcache/singleflight"
+	pb "zcache/zcachepb"
+)
+
+// BatchGetter 可选接口，Getter 实现后 GetMulti 会一次性加载所有本地未命中的 key
+// 返回结果中缺少的 key 视为不存在
+type BatchGetter interface {
+	GetMulti(ctx context.Context, keys []string) (map[string][]byte, error)
+}
+
+// BatchMetaGetter 可选接口，与 BatchGetter 相同，但同时返回每个 key 的缓存元信息，Group 会优先调用它
+// metas 中缺少的 key 使用空的元信息
+type BatchMetaGetter interface {
+	GetMultiWithMeta(ctx context.Context, keys []string) (values map[string][]byte, metas map[string]Meta, err error)
+}
+
+// GetMulti 批量获取数据，先查本地缓存，再将未命中的 key 按所属节点合并为一次请求，
+// 最后从数据源加载剩余的 key
+// 返回所有获取成功的值，部分 key 获取失败时同时返回汇总的错误
+func (g *Group) GetMulti(ctx context.Context, keys []string) (_ map[string]ByteView, err error) {
+	ctx, span := g.tracer.Start(ctx, spanGetMulti, slog.String("group", g.name), slog.Int("keys", len(keys)))
+	defer func() { span.End(err) }()
+	result := make(map[string]ByteView, len(keys))
+	var misses []string
+	var errs []error
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key == "" {
+			errs = append(errs, errEmptyKey)
+			continue
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		g.stats.gets.Add(1)
+		if v, hit := g.lookupCache(key); hit {
+			g.stats.cacheHits.Add(1)
+			result[key] = v
+			continue
+		}
+		g.stats.loads.Add(1)
+		misses = append(misses, key)
+	}
+	if len(misses) > 0 {
+		local := g.getMultiFromPeers(ctx, misses, result)
+		if err := g.getMultiLocally(ctx, local, result); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// getMultiFromPeers 按所属节点分组并发请求，返回需要在本地加载的 key
+// 远程节点获取失败的 key 与 load 一样退回本地加载
+func (g *Group) getMultiFromPeers(ctx context.Context, keys []string, result map[string]ByteView) []string {
+	if g.peers == nil || forwarded(ctx) {
+		return keys
+	}
+	var local []string
+	byPeer := make(map[PeerGetter][]string)
+	for _, key := range keys {
+		if peer, ok := g.peers.PickPeer(key); ok {
+			byPeer[peer] = append(byPeer[peer], key)
+		} else {
+			local = append(local, key)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for peer, peerKeys := range byPeer {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values, err := g.getMultiFromPeer(ctx, peer, peerKeys)
+			if err != nil {
+				g.logger.Warn("远程节点批量获取数据失败", "peer", peerName(peer), "err", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, key := range peerKeys {
+				if v, ok := values[key]; ok {
+					result[key] = v
+				} else {
+					local = append(local, key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return local
+}
+
+// getMultiFromPeer 向一个节点批量获取数据，节点不支持批量请求时逐个获取
+func (g *Group) getMultiFromPeer(ctx context.Context, peer PeerGetter, keys []string) (map[string]ByteView, error) {
+	values := make(map[string]ByteView, len(keys))
+	batch, ok := peer.(PeerBatchGetter)
+	if !ok {
+		var errs []error
+		for _, key := range keys {
+			v, err := g.getFromPeer(ctx, peer, key)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			values[key] = v
+		}
+		return values, errors.Join(errs...)
+	}
+
+	req := &pb.MultiRequest{Group: g.name, Keys: keys}
+	res := &pb.MultiResponse{}
+	ctx, span := g.tracer.Start(ctx, spanGetFromPeer, slog.Int("keys", len(keys)), slog.String("peer", peerName(peer)))
+	start := time.Now()
+	err := batch.GetMulti(ctx, req, res)
+	span.End(err)
+	g.peerLatency.get(peerName(peer)).observe(time.Since(start))
+	if err != nil {
+		g.stats.peerErrors.Add(1)
+		return nil, err
+	}
+	g.stats.peerLoads.Add(int64(len(res.GetValues())))
+	for key, b := range res.GetValues() {
+		values[key] = g.populateHotCache(key, b, res.GetMetas()[key])
+	}
+	return values, nil
+}
+
+// getMultiLocally 从数据源加载 key，Getter 实现了 BatchGetter 或 BatchMetaGetter 时只调用一次
+// 与 load 一样通过 singleflight 去重，正在被其他调用者加载的 key 等待已有的结果
+func (g *Group) getMultiLocally(ctx context.Context, keys []string, result map[string]ByteView) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	var errs []error
+	if !g.batchLoads() {
+		for _, key := range keys {
+			viewi, err := g.loader.DoContext(ctx, key, func() (interface{}, error) {
+				g.stats.loadsDeduped.Add(1)
+				g.stats.inFlight.Add(1)
+				defer g.stats.inFlight.Add(-1)
+				ctx, cancel := g.sharedContext(ctx)
+				defer cancel()
+				return g.getLocally(ctx, key)
+			})
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			result[key] = viewi.(ByteView)
+		}
+		return errors.Join(errs...)
+	}
+
+	results := g.loader.DoMulti(ctx, keys, func(keys []string) map[string]singleflight.Result {
+		ctx, cancel := g.sharedContext(ctx)
+		defer cancel()
+		return g.getMultiFromOrigin(ctx, keys)
+	})
+	for _, key := range keys {
+		if r := results[key]; r.Err != nil {
+			errs = append(errs, r.Err)
+		} else {
+			result[key] = r.Val.(ByteView)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// batchLoads 判断 Getter 是否支持批量加载
+func (g *Group) batchLoads() bool {
+	switch g.getter.(type) {
+	case BatchMetaGetter, BatchGetter:
+		return true
+	}
+	return false
+}
+
+// getMultiFromOrigin 一次性从数据源加载 keys，与 getLocally 一样按元信息写入缓存
+func (g *Group) getMultiFromOrigin(ctx context.Context, keys []string) map[string]singleflight.Result {
+	g.stats.loadsDeduped.Add(int64(len(keys)))
+	g.stats.inFlight.Add(1)
+	defer g.stats.inFlight.Add(-1)
+	ctx, span := g.tracer.Start(ctx, spanGetLocally, slog.Int("keys", len(keys)))
+	start := time.Now()
+	values, metas, err := g.fetchMulti(ctx, keys)
+	span.End(err)
+	g.originLatency.observe(time.Since(start))
+	results := make(map[string]singleflight.Result, len(keys))
+	for _, key := range keys {
+		if err != nil {
+			g.stats.localLoadErrs.Add(1)
+			results[key] = singleflight.Result{Err: err}
+			continue
+		}
+		b, ok := values[key]
+		if !ok {
+			g.stats.localLoadErrs.Add(1)
+			results[key] = singleflight.Result{Err: fmt.Errorf("%s not found", key)}
+			continue
+		}
+		g.stats.localLoads.Add(1)
+		meta := metas[key]
+		value := ByteView{b: cloneBytes(b), version: meta.Version, noStore: meta.NoStore}
+		if !meta.NoStore {
+			value = g.populateCache(key, value, meta.TTL)
+		}
+		results[key] = singleflight.Result{Val: value}
+	}
+	return results
+}
+
+// fetchMulti 调用批量回调获取源数据，BatchGetter 返回空的元信息
+func (g *Group) fetchMulti(ctx context.Context, keys []string) (map[string][]byte, map[string]Meta, error) {
+	if getter, ok := g.getter.(BatchMetaGetter); ok {
+		return getter.GetMultiWithMeta(ctx, keys)
+	}
+	values, err := g.getter.(BatchGetter).GetMulti(ctx, keys)
+	return values, nil, err
+}
+
+// multiResponse 将批量获取的结果编码为发给其他节点的响应
+func (g *Group) multiResponse(views map[string]ByteView) *pb.MultiResponse {
+	out := &pb.MultiResponse{
+		Values: make(map[string][]byte, len(views)),
+		Metas:  make(map[string]*pb.EntryMeta, len(views)),
+	}
+	for key, view := range views {
+		out.Values[key] = view.ByteSlice()
+		out.Metas[key] = g.entryMeta(view)
+	}
+	return out
+}
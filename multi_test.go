@@ -0,0 +1,196 @@
+package zcache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+	pb "zcache/zcachepb"
+)
+
+// batchPeer 支持批量请求的远程节点
+type batchPeer struct {
+	fakePeer
+	batches [][]string
+}
+
+func (p *batchPeer) GetMulti(_ context.Context, in *pb.MultiRequest, out *pb.MultiResponse) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batches = append(p.batches, in.GetKeys())
+	out.Values = make(map[string][]byte)
+	for _, key := range in.GetKeys() {
+		if v, ok := p.values[key]; ok {
+			out.Values[key] = v
+		}
+	}
+	return nil
+}
+
+// batchPicker 与 fakePicker 相同，但返回支持批量请求的节点
+type batchPicker map[byte]*batchPeer
+
+func (p batchPicker) PickPeer(key string) (PeerGetter, bool) {
+	if peer, ok := p[key[0]]; ok {
+		return peer, true
+	}
+	return nil, false
+}
+
+type batchGetter struct {
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (g *batchGetter) Get(key string) ([]byte, error) {
+	return nil, fmt.Errorf("Get should not be called")
+}
+
+func (g *batchGetter) GetMulti(_ context.Context, keys []string) (map[string][]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls = append(g.calls, keys)
+	values := make(map[string][]byte)
+	for _, key := range keys {
+		if key != "lmissing" {
+			values[key] = []byte("origin-" + key)
+		}
+	}
+	return values, nil
+}
+
+func TestGetMulti(t *testing.T) {
+	a := &batchPeer{fakePeer: fakePeer{values: map[string][]byte{"a1": []byte("A1"), "a2": []byte("A2")}}}
+	getter := &batchGetter{}
+	z := NewGroup("multi", 2<<10, getter)
+	z.RegisterPeers(batchPicker{'a': a})
+	z.setLocally("lcached", []byte("cached"))
+
+	keys := []string{"lcached", "a1", "a2", "a3", "l1", "lmissing", "l1"}
+	values, err := z.GetMulti(context.Background(), keys)
+	if err == nil {
+		t.Fatalf("expect error for lmissing")
+	}
+	expect := map[string]string{
+		"lcached": "cached",
+		"a1":      "A1",
+		"a2":      "A2",
+		"a3":      "origin-a3",
+		"l1":      "origin-l1",
+	}
+	if len(values) != len(expect) {
+		t.Fatalf("expect %d values, got %d", len(expect), len(values))
+	}
+	for k, v := range expect {
+		if values[k].String() != v {
+			t.Errorf("key %s: expect %q, got %q", k, v, values[k].String())
+		}
+	}
+
+	if len(a.batches) != 1 || len(a.batches[0]) != 3 {
+		t.Fatalf("expect one batch of 3 keys to peer a, got %v", a.batches)
+	}
+	if len(getter.calls) != 1 {
+		t.Fatalf("expect one batch call to the getter, got %v", getter.calls)
+	}
+	sort.Strings(getter.calls[0])
+	if fmt.Sprint(getter.calls[0]) != "[a3 l1 lmissing]" {
+		t.Fatalf("unexpected origin batch %v", getter.calls[0])
+	}
+
+	// 本地加载的值已写入缓存
+	if _, err := z.GetMulti(context.Background(), []string{"l1"}); err != nil || len(getter.calls) != 1 {
+		t.Fatalf("l1 should be served from cache, calls=%v err=%v", getter.calls, err)
+	}
+}
+
+// metaBatchGetter 返回元信息的批量回调，key 为 k 的单个加载会阻塞直到 release 关闭
+type metaBatchGetter struct {
+	mu      sync.Mutex
+	calls   [][]string
+	loads   int
+	batched chan struct{}
+	release chan struct{}
+}
+
+func (g *metaBatchGetter) Get(key string) ([]byte, error) {
+	<-g.release
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.loads++
+	return []byte("single-" + key), nil
+}
+
+func (g *metaBatchGetter) GetMultiWithMeta(_ context.Context, keys []string) (map[string][]byte, map[string]Meta, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls = append(g.calls, keys)
+	if g.batched != nil {
+		close(g.batched)
+		g.batched = nil
+	}
+	values := make(map[string][]byte, len(keys))
+	metas := map[string]Meta{
+		"nostore":   {NoStore: true},
+		"versioned": {Version: "v2", TTL: time.Minute},
+	}
+	for _, key := range keys {
+		values[key] = []byte("batch-" + key)
+	}
+	return values, metas, nil
+}
+
+func TestGetMultiMeta(t *testing.T) {
+	now := time.Now()
+	getter := &metaBatchGetter{}
+	z := NewGroup("multi-meta", 2<<10, getter, WithClock(func() time.Time { return now }))
+	values, err := z.GetMulti(context.Background(), []string{"nostore", "versioned"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := values["versioned"]; v.Version() != "v2" {
+		t.Fatalf("expect version v2, got %q", v.Version())
+	}
+	if _, ok := z.mainCache.get("nostore"); ok {
+		t.Fatalf("NoStore value should not be cached")
+	}
+	if _, ok := z.mainCache.get("versioned"); !ok {
+		t.Fatalf("versioned value should be cached")
+	}
+	now = now.Add(2 * time.Minute)
+	if _, ok := z.mainCache.get("versioned"); ok {
+		t.Fatalf("value should expire after the TTL from the getter")
+	}
+}
+
+func TestGetMultiSharesLoads(t *testing.T) {
+	batched := make(chan struct{})
+	getter := &metaBatchGetter{batched: batched, release: make(chan struct{})}
+	z := NewGroup("multi-shared", 2<<10, getter)
+	single := make(chan string, 1)
+	go func() {
+		v, _ := z.Get("k")
+		single <- v.String()
+	}()
+	waitFor(t, "Get to start loading", func() bool { return z.Stats().InFlight == 1 })
+
+	multi := make(chan map[string]ByteView, 1)
+	go func() {
+		values, _ := z.GetMulti(context.Background(), []string{"k", "x"})
+		multi <- values
+	}()
+	// k 正在被 Get 加载，批量回调只加载 x
+	<-batched
+	close(getter.release)
+	values := <-multi
+	if v := <-single; values["k"].String() != v || v != "single-k" {
+		t.Fatalf("GetMulti should share the in-flight load of k, got %q and %q", values["k"].String(), v)
+	}
+	getter.mu.Lock()
+	defer getter.mu.Unlock()
+	if fmt.Sprint(getter.calls) != "[[x]]" || getter.loads != 1 {
+		t.Fatalf("expect one single load and one batch [x], got loads=%d batches=%v", getter.loads, getter.calls)
+	}
+}
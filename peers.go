@@ -1,6 +1,9 @@
 package zcache
 
-import pb "zcache/zcachepb"
+import (
+	"context"
+	pb "zcache/zcachepb"
+)
 
 // PeerPicker 定义从其他节点获取数据的接口
 type PeerPicker interface {
@@ -8,6 +11,74 @@ type PeerPicker interface {
 }
 
 // PeerGetter 从远程节点获取缓存值，每个远程节点都实现了这个接口，用于获取缓存值
+// ctx 结束时应当尽快放弃请求并返回错误
 type PeerGetter interface {
+	Get(ctx context.Context, in *pb.Request, out *pb.Response) error
+}
+
+// LegacyPeerGetter 不带 ctx 的旧版 PeerGetter
+//
+// Deprecated: 请改为实现 PeerGetter，过渡期间可以使用 AdaptPeerGetter 包装
+type LegacyPeerGetter interface {
 	Get(in *pb.Request, out *pb.Response) error
 }
+
+// AdaptPeerGetter 将旧版 LegacyPeerGetter 包装为 PeerGetter
+// 旧版实现无法中途取消，ctx 只在发起请求前检查；peer 实现了 PeerNamer 时保留节点地址
+func AdaptPeerGetter(peer LegacyPeerGetter) PeerGetter {
+	return legacyPeerGetter{peer}
+}
+
+type legacyPeerGetter struct {
+	peer LegacyPeerGetter
+}
+
+func (l legacyPeerGetter) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return l.peer.Get(in, out)
+}
+
+// PeerName 实现了 PeerNamer 接口
+func (l legacyPeerGetter) PeerName() string {
+	if n, ok := l.peer.(PeerNamer); ok {
+		return n.PeerName()
+	}
+	return "unknown"
+}
+
+// PeerUpdater 可选接口，PeerGetter 实现后支持向远程节点写入和删除缓存值
+type PeerUpdater interface {
+	Set(ctx context.Context, in *pb.SetRequest) error
+	Remove(ctx context.Context, in *pb.RemoveRequest) error
+}
+
+// PeerLister 可选接口，PeerPicker 实现后可以列出除自身外的全部节点，用于广播失效通知
+type PeerLister interface {
+	Peers() []PeerGetter
+}
+
+// PeerBatchGetter 可选接口，PeerGetter 实现后 GetMulti 会将同一节点的 key 合并为一次请求
+type PeerBatchGetter interface {
+	GetMulti(ctx context.Context, in *pb.MultiRequest, out *pb.MultiResponse) error
+}
+
+// ReplicaPicker 可选接口，PeerPicker 实现后 Group 可以按顺序尝试 key 的多个副本节点
+type ReplicaPicker interface {
+	// PickPeers 按优先级返回 key 的前 n 个副本节点，第一个与 PickPeer 相同，本节点对应的位置为 nil
+	PickPeers(key string, n int) []PeerGetter
+}
+
+// PeerNamer 可选接口，PeerGetter 实现后返回节点地址，用作指标的 peer 标签
+type PeerNamer interface {
+	PeerName() string
+}
+
+// peerName 返回节点地址，PeerGetter 未实现 PeerNamer 时返回 "unknown"
+func peerName(peer PeerGetter) string {
+	if n, ok := peer.(PeerNamer); ok {
+		return n.PeerName()
+	}
+	return "unknown"
+}
@@ -0,0 +1,196 @@
+package zcache
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"zcache/consistenthash"
+)
+
+// peerSet 节点成员管理，由 HTTPPool 和 GRPCPool 嵌入
+// 维护节点列表、权重、健康状态、节点选择策略以及每个节点对应的 PeerGetter
+type peerSet[G PeerGetter] struct {
+	self         string                          // 本节点地址，选中时视为本地
+	newPlacement func() consistenthash.Placement // 创建节点选择策略
+	newGetter    func(peer string) (G, error)    // 创建访问节点的 PeerGetter
+	closeGetter  func(G)                         // 节点被删除或替换时调用，可以为 nil
+	logPick      func(peer, key string)          // 选中远程节点时调用，可以为 nil
+
+	mu        sync.Mutex // guards placement, weights, health and getters
+	placement consistenthash.Placement
+	weights   map[string]int         // 已注册节点的权重，节点恢复健康后按原权重重新加入
+	health    map[string]*peerHealth // 节点的健康状态
+	getters   map[string]G
+}
+
+// setWeighted 用新的节点选择策略替换全部节点，创建失败时保持原有节点不变
+func (s *peerSet[G]) setWeighted(weights map[string]int) error {
+	getters := make(map[string]G, len(weights))
+	for peer := range weights {
+		g, err := s.newGetter(peer)
+		if err != nil {
+			s.close(getters)
+			return err
+		}
+		getters[peer] = g
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.close(s.getters)
+	s.placement = s.newPlacement()
+	addWeighted(s.placement, weights)
+	s.weights = maps.Clone(weights)
+	s.health = make(map[string]*peerHealth, len(weights))
+	for peer := range weights {
+		s.health[peer] = &peerHealth{}
+	}
+	s.getters = getters
+	return nil
+}
+
+// addPeers 增量添加权重为 1 的节点，已存在的节点会被忽略
+func (s *peerSet[G]) addPeers(peers ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.placement == nil {
+		s.placement = s.newPlacement()
+		s.weights = make(map[string]int, len(peers))
+		s.health = make(map[string]*peerHealth, len(peers))
+		s.getters = make(map[string]G, len(peers))
+	}
+	for _, peer := range peers {
+		if _, ok := s.getters[peer]; ok {
+			continue
+		}
+		g, err := s.newGetter(peer)
+		if err != nil {
+			return err
+		}
+		s.placement.Add(peer)
+		s.weights[peer] = 1
+		s.health[peer] = &peerHealth{}
+		s.getters[peer] = g
+	}
+	return nil
+}
+
+// removePeers 增量删除节点
+func (s *peerSet[G]) removePeers(peers ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.placement == nil {
+		return
+	}
+	s.placement.Remove(peers...)
+	for _, peer := range peers {
+		if g, ok := s.getters[peer]; ok && s.closeGetter != nil {
+			s.closeGetter(g)
+		}
+		delete(s.weights, peer)
+		delete(s.health, peer)
+		delete(s.getters, peer)
+	}
+}
+
+// close 关闭 getters 中的全部 PeerGetter
+func (s *peerSet[G]) close(getters map[string]G) {
+	if s.closeGetter == nil {
+		return
+	}
+	for _, g := range getters {
+		s.closeGetter(g)
+	}
+}
+
+// PickPeer 根据具体的 key 选择节点，key 属于本节点时返回 false
+func (s *peerSet[G]) PickPeer(key string) (PeerGetter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.placement == nil {
+		return nil, false
+	}
+	if peer := s.placement.Get(key); peer != s.self && peer != "" {
+		if s.logPick != nil {
+			s.logPick(peer, key)
+		}
+		return s.getters[peer], true
+	}
+	return nil, false
+}
+
+// PickPeers 实现了 ReplicaPicker 接口，本节点对应的位置为 nil
+// 选择策略未实现 consistenthash.ReplicatedPlacement 时只返回一个节点
+func (s *peerSet[G]) PickPeers(key string, n int) []PeerGetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.placement == nil {
+		return nil
+	}
+	nodes := pickReplicas(s.placement, key, n)
+	peers := make([]PeerGetter, len(nodes))
+	for i, node := range nodes {
+		if node != s.self {
+			peers[i] = s.getters[node]
+		}
+	}
+	return peers
+}
+
+// Peers 实现了 PeerLister 接口，返回除自身外的全部节点
+func (s *peerSet[G]) Peers() []PeerGetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	peers := make([]PeerGetter, 0, len(s.getters))
+	for peer, getter := range s.getters {
+		if peer != s.self {
+			peers = append(peers, getter)
+		}
+	}
+	return peers
+}
+
+// Healthy 返回节点当前是否参与节点选择
+func (s *peerSet[G]) Healthy(peer string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.health[peer]
+	return ok && !h.ejected
+}
+
+// eject 将节点移出节点选择，调用方需持有 mu
+func (s *peerSet[G]) eject(peer string) {
+	s.placement.Remove(peer)
+}
+
+// readmit 按原权重将节点重新加入节点选择，调用方需持有 mu
+func (s *peerSet[G]) readmit(peer string) {
+	addWeighted(s.placement, map[string]int{peer: s.weights[peer]})
+}
+
+// addWeighted 按权重将节点加入节点选择策略，策略不支持权重时忽略权重
+// 节点按名称排序后加入，使用相同节点列表的各个节点对 key 的分配保持一致
+func addWeighted(placement consistenthash.Placement, weights map[string]int) {
+	if wp, ok := placement.(consistenthash.WeightedPlacement); ok {
+		wp.AddWeighted(weights)
+		return
+	}
+	placement.Add(slices.Sorted(maps.Keys(weights))...)
+}
+
+// pickReplicas 按优先级返回 key 的前 n 个不同节点，第一个与 placement.Get 相同
+// 选择策略未实现 consistenthash.ReplicatedPlacement 时只返回一个节点
+func pickReplicas(placement consistenthash.Placement, key string, n int) []string {
+	first := placement.Get(key)
+	if first == "" {
+		return nil
+	}
+	nodes := []string{first}
+	if rp, ok := placement.(consistenthash.ReplicatedPlacement); ok {
+		for _, node := range rp.GetN(key, n) {
+			if node != first && len(nodes) < n {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	return nodes
+}
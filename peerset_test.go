@@ -0,0 +1,80 @@
+package zcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"zcache/consistenthash"
+	pb "zcache/zcachepb"
+)
+
+// namedPeer 只记录地址和是否被关闭的 PeerGetter
+type namedPeer struct {
+	name   string
+	closed bool
+}
+
+func (p *namedPeer) Get(context.Context, *pb.Request, *pb.Response) error {
+	return nil
+}
+
+func newTestPeerSet() *peerSet[*namedPeer] {
+	return &peerSet[*namedPeer]{
+		self: "self",
+		newPlacement: func() consistenthash.Placement {
+			return consistenthash.NewRendezvous(nil)
+		},
+		newGetter: func(peer string) (*namedPeer, error) {
+			if peer == "bad" {
+				return nil, errors.New("bad peer")
+			}
+			return &namedPeer{name: peer}, nil
+		},
+		closeGetter: func(p *namedPeer) {
+			p.closed = true
+		},
+	}
+}
+
+func TestPeerSet(t *testing.T) {
+	s := newTestPeerSet()
+	if err := s.setWeighted(map[string]int{"self": 1, "a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	a := s.getters["a"]
+
+	// 创建失败时保持原有节点不变
+	if err := s.setWeighted(map[string]int{"self": 1, "b": 1, "bad": 1}); err == nil {
+		t.Fatalf("expect an error for a bad peer")
+	}
+	if a.closed || len(s.Peers()) != 1 || s.Peers()[0] != a {
+		t.Fatalf("failed set should keep the old peers")
+	}
+
+	if err := s.setWeighted(map[string]int{"self": 1, "b": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !a.closed || !s.Healthy("b") || s.Healthy("a") {
+		t.Fatalf("replaced peers should be closed")
+	}
+
+	// 移出节点选择后按原权重重新加入
+	s.mu.Lock()
+	s.eject("b")
+	s.mu.Unlock()
+	if remoteShare(s) != 0 {
+		t.Fatalf("ejected peer should not be picked")
+	}
+	s.mu.Lock()
+	s.readmit("b")
+	s.mu.Unlock()
+	if remoteShare(s) == 0 {
+		t.Fatalf("readmitted peer should be picked again")
+	}
+
+	b := s.getters["b"]
+	s.removePeers("b")
+	if !b.closed || len(s.Peers()) != 0 || remoteShare(s) != 0 {
+		t.Fatalf("removed peer should be closed and no longer picked")
+	}
+}
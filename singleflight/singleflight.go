@@ -1,50 +1,186 @@
 package singleflight
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrPanicked 正在执行的 fn 发生了 panic，等待同一个 key 的其他调用者收到该错误
+var ErrPanicked = errors.New("singleflight: fn panicked")
 
 // call 代表正在进行中，或已经结束的请求
 type call struct {
-	wg  sync.WaitGroup // 避免重入
-	val interface{}
-	err error
+	done  chan struct{} // 请求结束后关闭，等待者据此获取结果
+	val   interface{}
+	err   error
+	panic interface{} // fn panic 时的值，此时 err 为 ErrPanicked
 }
 
 // Group 主数据结构，管理不同 key 的请求
 type Group struct {
 	mu sync.Mutex // 保护 m 不被并发读写
 	m  map[string]*call
+
+	joinHook func(key string) // 仅用于测试，调用者开始等待进行中的请求时调用，可以为 nil
+}
+
+// joined 通知测试有调用者开始等待 key 的进行中请求，调用方需持有 mu
+func (g *Group) joined(key string) {
+	if g.joinHook != nil {
+		g.joinHook(key)
+	}
 }
 
 // Do 针对相同的 key，无论 Do 被调用多少次，函数 fn 都只会被调用一次，等待 fn 调用结束返回返回值或错误
 func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	return g.DoContext(context.Background(), key, fn)
+}
+
+// DoContext 与 Do 相同，但 ctx 结束时提前返回 ctx.Err()，发起请求的调用者也是如此
+// fn 在单独的协程中执行完毕，其结果留给其他仍在等待的调用者，因此 fn 不应依赖某一个调用者的 ctx
+// fn panic 时，发起请求的调用者如果仍在等待会收到同样的 panic，其他等待者收到 ErrPanicked
+func (g *Group) DoContext(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	g.mu.Lock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
 	}
 	if c, ok := g.m[key]; ok {
+		g.joined(key)
 		g.mu.Unlock()
 		// 请求进行中，等待
-		c.wg.Wait()
-		// 请求结束，返回结果
-		return c.val, c.err
+		select {
+		case <-c.done:
+			// 请求结束，返回结果
+			return c.val, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 	// 第一次，请求还没有进行，准备发起请求
-	c := new(call)
-	// 发起请求前加锁
-	c.wg.Add(1)
+	c := &call{done: make(chan struct{})}
 	// 添加到 g.m，表明 key 已经有对应的请求在处理
 	g.m[key] = c
 	g.mu.Unlock()
 
+	go g.call(c, key, fn)
+	select {
+	case <-c.done:
+		if c.panic != nil {
+			panic(c.panic)
+		}
+		return c.val, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// call 调用 fn 并结束请求
+func (g *Group) call(c *call, key string, fn func() (interface{}, error)) {
+	// fn panic 时同样需要结束请求，否则等待者会一直阻塞，key 也无法再次加载
+	defer func() {
+		if r := recover(); r != nil {
+			c.val, c.err, c.panic = nil, ErrPanicked, r
+		}
+		// 请求结束
+		close(c.done)
+
+		g.mu.Lock()
+		// 更新 g.m
+		delete(g.m, key)
+		g.mu.Unlock()
+	}()
+
 	// 调用 fn，发起请求
 	c.val, c.err = fn()
-	// 请求结束
-	c.wg.Done()
+}
+
+// Result DoMulti 中一个 key 的结果
+type Result struct {
+	Val interface{}
+	Err error
+}
 
+// DoMulti 批量版本的 DoContext：keys 中没有进行中请求的 key 合并为一次 fn 调用，
+// 其余 key 等待已有的请求，返回每个 key 的结果
+// fn 的参数是本次负责加载的 key，返回值中缺少的 key 收到错误；ctx 结束时尚未完成的 key 收到 ctx.Err()
+func (g *Group) DoMulti(ctx context.Context, keys []string, fn func(keys []string) map[string]Result) map[string]Result {
+	results := make(map[string]Result, len(keys))
+	if err := ctx.Err(); err != nil {
+		for _, key := range keys {
+			results[key] = Result{Err: err}
+		}
+		return results
+	}
 	g.mu.Lock()
-	// 更新 g.m
-	delete(g.m, key)
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	calls := make(map[string]*call, len(keys))
+	owned := make(map[string]*call)
+	var loading []string
+	for _, key := range keys {
+		if _, ok := calls[key]; ok {
+			continue
+		}
+		if c, ok := g.m[key]; ok {
+			g.joined(key)
+			calls[key] = c
+			continue
+		}
+		c := &call{done: make(chan struct{})}
+		g.m[key] = c
+		calls[key] = c
+		owned[key] = c
+		loading = append(loading, key)
+	}
 	g.mu.Unlock()
 
-	return c.val, c.err
+	if len(loading) > 0 {
+		go g.callMulti(owned, loading, fn)
+	}
+	for key, c := range calls {
+		select {
+		case <-c.done:
+			if _, ok := owned[key]; ok && c.panic != nil {
+				panic(c.panic)
+			}
+			results[key] = Result{Val: c.val, Err: c.err}
+		case <-ctx.Done():
+			results[key] = Result{Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+// callMulti 调用 fn 加载 keys 并结束它们对应的请求
+func (g *Group) callMulti(calls map[string]*call, keys []string, fn func(keys []string) map[string]Result) {
+	defer func() {
+		r := recover()
+		for _, c := range calls {
+			if r != nil {
+				c.val, c.err, c.panic = nil, ErrPanicked, r
+			}
+			close(c.done)
+		}
+
+		g.mu.Lock()
+		for key := range calls {
+			delete(g.m, key)
+		}
+		g.mu.Unlock()
+	}()
+
+	results := fn(keys)
+	for key, c := range calls {
+		r, ok := results[key]
+		if !ok {
+			r.Err = fmt.Errorf("singleflight: no result for key %q", key)
+		}
+		c.val, c.err = r.Val, r.Err
+	}
 }
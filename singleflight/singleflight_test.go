@@ -0,0 +1,217 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// watchJoins 记录开始等待进行中请求的调用者，需要在使用 g 之前调用
+func watchJoins(g *Group) <-chan string {
+	joins := make(chan string, 16)
+	g.joinHook = func(key string) {
+		joins <- key
+	}
+	return joins
+}
+
+// waitJoin 等待有调用者开始等待 key 的进行中请求
+func waitJoin(t *testing.T, joins <-chan string, key string) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case k := <-joins:
+			if k == key {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for a caller to join %q", key)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	var g Group
+	v, err := g.Do("key", func() (interface{}, error) {
+		return "bar", nil
+	})
+	if v.(string) != "bar" || err != nil {
+		t.Fatalf("Do = %v, %v", v, err)
+	}
+}
+
+func TestDoDupSuppress(t *testing.T) {
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "bar", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := g.Do("key", fn); v.(string) != "bar" || err != nil {
+				t.Errorf("Do = %v, %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("number of calls = %d; want 1", got)
+	}
+}
+
+func TestDoContextCancel(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_, _ = g.Do("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			return "bar", nil
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := g.DoContext(ctx, "key", func() (interface{}, error) {
+		t.Fatal("fn should not be called while another call is in flight")
+		return nil, nil
+	}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect DeadlineExceeded, got %v", err)
+	}
+	close(release)
+}
+
+func TestDoContextLeaderCancel(t *testing.T) {
+	var g Group
+	joins := watchJoins(&g)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := g.DoContext(ctx, "key", func() (interface{}, error) {
+			close(started)
+			<-release
+			return "bar", nil
+		})
+		leader <- err
+	}()
+	<-started
+
+	// 发起请求的调用者取消后立即返回，fn 继续执行
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect leader to get Canceled, got %v", err)
+	}
+	// fn 结束前 key 仍在进行中，之后的调用者等待它的结果
+	waiter := make(chan interface{}, 1)
+	go func() {
+		v, _ := g.Do("key", func() (interface{}, error) {
+			return nil, errors.New("waiter should not run fn")
+		})
+		waiter <- v
+	}()
+	waitJoin(t, joins, "key")
+	close(release)
+	if v := <-waiter; v != "bar" {
+		t.Fatalf("waiter should get the result after the leader gave up, got %v", v)
+	}
+}
+
+func TestDoPanic(t *testing.T) {
+	var g Group
+	joins := watchJoins(&g)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	panicked := make(chan bool, 1)
+	go func() {
+		defer func() {
+			panicked <- recover() != nil
+		}()
+		_, _ = g.Do("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+
+	<-started
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := g.Do("key", func() (interface{}, error) {
+			return nil, errors.New("waiter should not run fn")
+		})
+		waiter <- err
+	}()
+	waitJoin(t, joins, "key")
+	close(release)
+
+	if !<-panicked {
+		t.Fatalf("expect the panic to reach the caller running fn")
+	}
+	if err := <-waiter; !errors.Is(err, ErrPanicked) {
+		t.Fatalf("expect waiters to get ErrPanicked, got %v", err)
+	}
+	// key 不会被永久占用
+	if v, err := g.Do("key", func() (interface{}, error) { return "bar", nil }); err != nil || v != "bar" {
+		t.Fatalf("Do after panic = %v, %v", v, err)
+	}
+}
+
+func TestDoMulti(t *testing.T) {
+	var g Group
+	joins := watchJoins(&g)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_, _ = g.Do("a", func() (interface{}, error) {
+			close(started)
+			<-release
+			return "A", nil
+		})
+	}()
+	<-started
+
+	var batches [][]string
+	done := make(chan map[string]Result, 1)
+	go func() {
+		done <- g.DoMulti(context.Background(), []string{"a", "b", "c", "b"}, func(keys []string) map[string]Result {
+			batches = append(batches, keys)
+			return map[string]Result{"b": {Val: "B"}}
+		})
+	}()
+	// a 等待已有的请求，b 和 c 合并为一次调用
+	waitJoin(t, joins, "a")
+	close(release)
+	results := <-done
+	if fmt.Sprint(batches) != "[[b c]]" {
+		t.Fatalf("expect one batch for b and c, got %v", batches)
+	}
+	if results["a"].Val != "A" || results["b"].Val != "B" {
+		t.Fatalf("unexpected results %v", results)
+	}
+	if results["c"].Err == nil {
+		t.Fatalf("expect an error for a key missing from the batch result")
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.m) != 0 {
+		t.Fatalf("all calls should be finished, got %v", g.m)
+	}
+}
@@ -0,0 +1,75 @@
+package zcache
+
+import "sync/atomic"
+
+// Stats Group 的统计数据
+type Stats struct {
+	Gets           int64 // Get 和 GetMulti 请求的 key 总数
+	CacheHits      int64 // 命中主缓存或热点缓存的次数
+	PeerLoads      int64 // 从远程节点获取成功的次数，包括重试和对冲请求
+	PeerErrors     int64 // 从远程节点获取失败的次数
+	Loads          int64 // 缓存未命中的次数，即 Gets - CacheHits
+	LoadsDeduped   int64 // 经过 singleflight 合并后实际执行加载的次数
+	LocalLoads     int64 // 从数据源加载成功的次数
+	LocalLoadErrs  int64 // 从数据源加载失败的次数
+	ServerRequests int64 // 收到其他节点发来的 Get 请求数
+	InFlight       int64 // 正在进行的加载数
+}
+
+// CacheType 缓存的类型
+type CacheType int
+
+const (
+	MainCache CacheType = iota + 1 // 本节点负责的 key
+	HotCache                       // 从远程节点获取的热点 key
+)
+
+// CacheStats 单个缓存的统计数据
+type CacheStats struct {
+	Bytes     int64 // 占用的字节数
+	Items     int64 // 缓存值的数量
+	Gets      int64 // 查询次数
+	Hits      int64 // 命中次数
+	Evictions int64 // 因容量不足或过期被淘汰的次数，不包括主动删除
+}
+
+// groupStats Group 内部使用的原子计数器
+type groupStats struct {
+	gets           atomic.Int64
+	cacheHits      atomic.Int64
+	peerLoads      atomic.Int64
+	peerErrors     atomic.Int64
+	loads          atomic.Int64
+	loadsDeduped   atomic.Int64
+	localLoads     atomic.Int64
+	localLoadErrs  atomic.Int64
+	serverRequests atomic.Int64
+	inFlight       atomic.Int64
+}
+
+// Stats 返回 Group 统计数据的快照
+func (g *Group) Stats() Stats {
+	return Stats{
+		Gets:           g.stats.gets.Load(),
+		CacheHits:      g.stats.cacheHits.Load(),
+		PeerLoads:      g.stats.peerLoads.Load(),
+		PeerErrors:     g.stats.peerErrors.Load(),
+		Loads:          g.stats.loads.Load(),
+		LoadsDeduped:   g.stats.loadsDeduped.Load(),
+		LocalLoads:     g.stats.localLoads.Load(),
+		LocalLoadErrs:  g.stats.localLoadErrs.Load(),
+		ServerRequests: g.stats.serverRequests.Load(),
+		InFlight:       g.stats.inFlight.Load(),
+	}
+}
+
+// CacheStats 返回主缓存或热点缓存的统计数据
+func (g *Group) CacheStats(which CacheType) CacheStats {
+	switch which {
+	case MainCache:
+		return g.mainCache.stats()
+	case HotCache:
+		return g.hotCache.stats()
+	}
+	return CacheStats{}
+}
@@ -0,0 +1,58 @@
+package zcache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGroupStats(t *testing.T) {
+	z := NewGroup("stats", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			if key == "lerr" {
+				return nil, errors.New("origin error")
+			}
+			return []byte("origin"), nil
+		}),
+		WithHotCache(0.5, 1),
+	)
+	peer := &fakePeer{values: map[string][]byte{"r1": []byte("remote")}}
+	z.RegisterPeers(&fakePicker{peers: map[byte]*fakePeer{'r': peer}})
+
+	_, _ = z.Get("l1")   // 本地加载
+	_, _ = z.Get("l1")   // 命中主缓存
+	_, _ = z.Get("lerr") // 数据源返回错误
+	_, _ = z.Get("r1")   // 从远程节点获取，写入热点缓存
+	_, _ = z.Get("r1")   // 命中热点缓存
+	_, _ = z.Get("r2")   // 远程节点失败，退回本地加载
+
+	expect := Stats{
+		Gets:          6,
+		CacheHits:     2,
+		PeerLoads:     1,
+		PeerErrors:    1,
+		Loads:         4,
+		LoadsDeduped:  4,
+		LocalLoads:    2,
+		LocalLoadErrs: 1,
+	}
+	if s := z.Stats(); s != expect {
+		t.Fatalf("expect %+v, got %+v", expect, s)
+	}
+	if s := z.CacheStats(MainCache); s.Items != 2 || s.Hits != 1 || s.Bytes == 0 {
+		t.Fatalf("unexpected main cache stats %+v", s)
+	}
+	if s := z.CacheStats(HotCache); s.Items != 1 || s.Hits != 1 {
+		t.Fatalf("unexpected hot cache stats %+v", s)
+	}
+}
+
+func TestCacheEvictions(t *testing.T) {
+	c := &cache{cacheBytes: 10}
+	c.add("k1", ByteView{b: []byte("12345")}, time.Time{})
+	c.add("k2", ByteView{b: []byte("12345")}, time.Time{})
+	c.remove("k2")
+	if s := c.stats(); s.Evictions != 1 || s.Items != 0 || s.Bytes != 0 {
+		t.Fatalf("only capacity evictions should be counted, got %+v", s)
+	}
+}
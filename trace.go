@@ -0,0 +1,200 @@
+package zcache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 追踪的 span 名称
+const (
+	spanGet           = "zcache.Get"
+	spanGetMulti      = "zcache.GetMulti"
+	spanLoad          = "zcache.singleflight"
+	spanGetFromPeer   = "zcache.getFromPeer"
+	spanGetLocally    = "zcache.getLocally"
+	spanSetLocally    = "zcache.setLocally"
+	spanRemoveLocally = "zcache.removeLocally"
+)
+
+// traceparentHeader W3C Trace Context 使用的请求头
+const traceparentHeader = "traceparent"
+
+// Tracer 追踪钩子，Group 在 Get、singleflight 等待、getFromPeer、getLocally 以及处理其他节点的写入和删除前后调用，
+// HTTPPool 和 GRPCPool 通过 Inject 和 Extract 在节点之间传递追踪上下文
+// 可以基于 OpenTelemetry 等实现，默认什么也不做
+type Tracer interface {
+	// Start 开始一个 span，返回携带该 span 的 ctx，之后的 span 以它为父 span
+	Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span)
+
+	// Inject 将 ctx 中的追踪上下文写入发往其他节点的请求头
+	Inject(ctx context.Context, header http.Header)
+
+	// Extract 从其他节点发来的请求头中恢复追踪上下文
+	Extract(ctx context.Context, header http.Header) context.Context
+}
+
+// Span 一次被追踪的操作
+type Span interface {
+	// SetAttributes 添加属性，例如是否命中缓存
+	SetAttributes(attrs ...slog.Attr)
+
+	// End 结束 span，err 为操作的结果
+	End(err error)
+}
+
+// NoopTracer 默认的 Tracer，什么也不做
+type NoopTracer struct{}
+
+func (NoopTracer) Start(ctx context.Context, _ string, _ ...slog.Attr) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (NoopTracer) Inject(context.Context, http.Header) {}
+
+func (NoopTracer) Extract(ctx context.Context, _ http.Header) context.Context {
+	return ctx
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...slog.Attr) {}
+
+func (noopSpan) End(error) {}
+
+// WithTracer 设置 Group 使用的 Tracer，默认为 NoopTracer
+func WithTracer(tracer Tracer) GroupOption {
+	return func(g *Group) {
+		g.tracer = tracer
+	}
+}
+
+// RecordedSpan TraceRecorder 记录的 span
+type RecordedSpan struct {
+	Name     string
+	TraceID  string
+	SpanID   string
+	ParentID string // 父 span 的 ID，根 span 为空
+	Attrs    []slog.Attr
+	Err      error
+	Start    time.Time
+	End      time.Time
+}
+
+// Attr 返回名为 key 的属性值
+func (s RecordedSpan) Attr(key string) (slog.Value, bool) {
+	for _, a := range s.Attrs {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+// TraceRecorder 将 span 保存在内存中的 Tracer，用于测试和调试
+// 追踪上下文按 W3C traceparent 格式在节点之间传递
+type TraceRecorder struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+// NewTraceRecorder 创建一个 TraceRecorder
+func NewTraceRecorder() *TraceRecorder {
+	return &TraceRecorder{}
+}
+
+// traceKey ctx 中保存当前 spanContext 的 key
+type traceKey struct{}
+
+type spanContext struct {
+	traceID, spanID string
+}
+
+func (r *TraceRecorder) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span) {
+	parent, _ := ctx.Value(traceKey{}).(spanContext)
+	traceID := parent.traceID
+	if traceID == "" {
+		traceID = randomID(16)
+	}
+	s := &recorderSpan{rec: r, span: RecordedSpan{
+		Name:     name,
+		TraceID:  traceID,
+		SpanID:   randomID(8),
+		ParentID: parent.spanID,
+		Attrs:    attrs,
+		Start:    time.Now(),
+	}}
+	return context.WithValue(ctx, traceKey{}, spanContext{traceID, s.span.SpanID}), s
+}
+
+func (r *TraceRecorder) Inject(ctx context.Context, header http.Header) {
+	if sc, ok := ctx.Value(traceKey{}).(spanContext); ok {
+		header.Set(traceparentHeader, fmt.Sprintf("00-%s-%s-01", sc.traceID, sc.spanID))
+	}
+}
+
+func (r *TraceRecorder) Extract(ctx context.Context, header http.Header) context.Context {
+	parts := strings.Split(header.Get(traceparentHeader), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	return context.WithValue(ctx, traceKey{}, spanContext{parts[1], parts[2]})
+}
+
+// Spans 返回已结束的 span，按结束的先后排列
+func (r *TraceRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := make([]RecordedSpan, len(r.spans))
+	copy(spans, r.spans)
+	return spans
+}
+
+// Reset 清空已记录的 span
+func (r *TraceRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
+
+type recorderSpan struct {
+	rec  *TraceRecorder
+	mu   sync.Mutex
+	span RecordedSpan
+}
+
+func (s *recorderSpan) SetAttributes(attrs ...slog.Attr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.span.Attrs = append(s.span.Attrs, attrs...)
+}
+
+func (s *recorderSpan) End(err error) {
+	s.mu.Lock()
+	span := s.span
+	s.mu.Unlock()
+	span.Err = err
+	span.End = time.Now()
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	s.rec.spans = append(s.rec.spans, span)
+}
+
+// randomID 返回 n 字节随机数的十六进制表示
+func randomID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// 静态类型检查
+var (
+	_ Tracer = NoopTracer{}
+	_ Tracer = (*TraceRecorder)(nil)
+)
@@ -0,0 +1,168 @@
+package zcache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	pb "zcache/zcachepb"
+)
+
+// spanByName 返回第一个名为 name 的 span
+func spanByName(t *testing.T, spans []RecordedSpan, name string) RecordedSpan {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("span %s not found in %v", name, spans)
+	return RecordedSpan{}
+}
+
+func TestTracing(t *testing.T) {
+	rec := NewTraceRecorder()
+	z := NewGroup("trace", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}),
+		WithTracer(rec),
+	)
+	peer := &fakePeer{values: map[string][]byte{"r": []byte("remote")}}
+	z.RegisterPeers(&fakePicker{peers: map[byte]*fakePeer{'r': peer}})
+
+	_, _ = z.Get("l")
+	spans := rec.Spans()
+	get := spanByName(t, spans, spanGet)
+	load := spanByName(t, spans, spanLoad)
+	local := spanByName(t, spans, spanGetLocally)
+	if get.ParentID != "" || load.ParentID != get.SpanID || local.ParentID != load.SpanID {
+		t.Fatalf("unexpected span tree %+v", spans)
+	}
+	if local.TraceID != get.TraceID || load.TraceID != get.TraceID {
+		t.Fatalf("spans should share the trace id")
+	}
+	if v, _ := get.Attr("hit"); v.Bool() {
+		t.Fatalf("first Get should miss")
+	}
+	if v, _ := load.Attr("shared"); v.Bool() {
+		t.Fatalf("the only caller should run the load itself")
+	}
+
+	rec.Reset()
+	_, _ = z.Get("l")
+	if spans := rec.Spans(); len(spans) != 1 {
+		t.Fatalf("cache hit should only record the Get span, got %v", spans)
+	} else if v, _ := spans[0].Attr("hit"); !v.Bool() {
+		t.Fatalf("second Get should hit")
+	}
+
+	rec.Reset()
+	_, _ = z.Get("r")
+	fromPeer := spanByName(t, rec.Spans(), spanGetFromPeer)
+	if v, _ := fromPeer.Attr("peer"); v.String() != "unknown" || fromPeer.Err != nil {
+		t.Fatalf("unexpected peer span %+v", fromPeer)
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	rec := NewTraceRecorder()
+	z := NewGroup("trace-http", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}),
+		WithTracer(rec),
+	)
+	srv := httptest.NewServer(NewHTTPPoolOpts("self", &HTTPPoolOptions{Tracer: rec}))
+	defer srv.Close()
+
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{Tracer: rec})
+	pool.Set("http://self", srv.URL)
+	key, peer := remoteKey(t, pool)
+	ctx, span := rec.Start(context.Background(), "client")
+	err := peer.Get(ctx, &pb.Request{Group: z.name, Key: key}, &pb.Response{})
+	span.End(err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := rec.Spans()
+	client := spanByName(t, spans, "client")
+	server := spanByName(t, spans, spanGet)
+	if server.TraceID != client.TraceID || server.ParentID != client.SpanID {
+		t.Fatalf("server span should continue the client trace, got %+v and %+v", client, server)
+	}
+}
+
+// checkPropagation 经由 peer 发送 Get、Set 和 Remove 请求，检查服务端的 span 延续了客户端的追踪
+func checkPropagation(t *testing.T, rec *TraceRecorder, group string, key string, peer PeerGetter) {
+	t.Helper()
+	updater := peer.(PeerUpdater)
+	for name, call := range map[string]func(ctx context.Context) error{
+		spanGet: func(ctx context.Context) error {
+			return peer.Get(ctx, &pb.Request{Group: group, Key: key}, &pb.Response{})
+		},
+		spanSetLocally: func(ctx context.Context) error {
+			return updater.Set(ctx, &pb.SetRequest{Group: group, Key: key, Value: []byte("v")})
+		},
+		spanRemoveLocally: func(ctx context.Context) error {
+			return updater.Remove(ctx, &pb.RemoveRequest{Group: group, Key: key})
+		},
+	} {
+		ctx, span := rec.Start(context.Background(), "client "+name)
+		err := call(ctx)
+		span.End(err)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		spans := rec.Spans()
+		client := spanByName(t, spans, "client "+name)
+		server := spanByName(t, spans, name)
+		if server.TraceID != client.TraceID || server.ParentID != client.SpanID {
+			t.Fatalf("%s: server span should continue the client trace, got %+v and %+v", name, client, server)
+		}
+	}
+}
+
+func TestTracePropagationUpdates(t *testing.T) {
+	rec := NewTraceRecorder()
+	z := NewGroup("trace-http-updates", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}),
+		WithTracer(rec),
+	)
+	srv := httptest.NewServer(NewHTTPPoolOpts("self", &HTTPPoolOptions{Tracer: rec}))
+	defer srv.Close()
+
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{Tracer: rec})
+	pool.Set("http://self", srv.URL)
+	key, peer := remoteKey(t, pool)
+	checkPropagation(t, rec, z.name, key, peer)
+}
+
+func TestTracePropagationGRPC(t *testing.T) {
+	rec := NewTraceRecorder()
+	z := NewGroup("trace-grpc", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}),
+		WithTracer(rec),
+	)
+	pool := startGRPCPool(t)
+	pool.SetTracer(rec)
+	key, peer := remoteKey(t, pool)
+	checkPropagation(t, rec, z.name, key, peer)
+}
+
+func TestTraceExtractInvalid(t *testing.T) {
+	rec := NewTraceRecorder()
+	h := http.Header{}
+	h.Set(traceparentHeader, "garbage")
+	ctx := rec.Extract(context.Background(), h)
+	_, span := rec.Start(ctx, "root")
+	span.End(nil)
+	if s := rec.Spans()[0]; s.ParentID != "" || len(s.TraceID) != 32 {
+		t.Fatalf("invalid traceparent should start a new trace, got %+v", s)
+	}
+}
@@ -1,20 +1,147 @@
 package zcache
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 	"zcache/singleflight"
 	pb "zcache/zcachepb"
 )
 
+// defaultLoadTimeout 共享加载默认的超时时间
+const defaultLoadTimeout = time.Minute
+
 // Group 是 zcache 最核心的数据结构，负责与用户的交互，并且控制缓存值存储和获取的流程
 type Group struct {
 	name      string              // 缓存的名称
 	getter    Getter              // 缓存未命中时获取源数据的回调
-	mainCache cache               // 缓存主体
+	mainCache cache               // 缓存主体，存放本节点负责的 key
+	hotCache  cache               // 热点缓存，存放从远程节点获取的部分热点 key，默认关闭
 	peers     PeerPicker          // 节点选择器
 	loader    *singleflight.Group // 同一个 key 每个节点只被访问一次，防止缓存击穿
+
+	loadTimeout time.Duration // singleflight 共享加载的超时时间
+
+	ttl      time.Duration                  // 缓存值默认的过期时间，为 0 表示永不过期
+	keyTTL   func(key string) time.Duration // 按 key 覆盖默认过期时间，可以为 nil
+	now      func() time.Time               // 时钟，便于测试时注入
+	interval time.Duration                  // 后台清理过期缓存的间隔，为 0 表示不启动
+	stop     chan struct{}                  // 关闭后停止后台清理
+	stopOnce sync.Once
+
+	hotRatio float64 // 热点缓存占 cacheBytes 的比例
+	hotRate  float64 // 从远程节点获取的值写入热点缓存的概率
+
+	replicas      int  // 每个 key 的副本数，大于 1 时按顺序尝试多个副本节点
+	replicaWrites bool // Set 时是否同时写入所有副本节点
+
+	hedging      bool           // 是否向下一个副本节点发送对冲请求
+	hedgeDelay   time.Duration  // 发送对冲请求前的等待时间，为 0 时按最近请求耗时的 p95 计算
+	latency      *latencyWindow // 最近远程请求的耗时，用于计算对冲等待时间
+	retries      int            // 远程节点返回暂时性错误时的最大重试次数
+	retryBackoff time.Duration  // 重试的基础等待时间
+
+	logger      *slog.Logger // 日志，带有 group 属性
+	logRequests bool         // 是否以 Debug 级别记录每次缓存命中
+	tracer      Tracer       // 追踪钩子，默认为 NoopTracer
+
+	stats         groupStats     // 统计数据
+	originLatency *histogram     // 从数据源加载的耗时
+	peerLatency   peerHistograms // 从远程节点获取的耗时
+}
+
+// GroupOption 配置 Group 的可选项
+type GroupOption func(*Group)
+
+// WithTTL 设置缓存值默认的过期时间
+func WithTTL(ttl time.Duration) GroupOption {
+	return func(g *Group) {
+		g.ttl = ttl
+	}
+}
+
+// WithKeyTTL 按 key 覆盖默认的过期时间
+// fn 返回正数时使用该值，返回 0 时使用默认值，返回负数时永不过期
+func WithKeyTTL(fn func(key string) time.Duration) GroupOption {
+	return func(g *Group) {
+		g.keyTTL = fn
+	}
+}
+
+// WithClock 注入时钟，默认为 time.Now
+func WithClock(now func() time.Time) GroupOption {
+	return func(g *Group) {
+		g.now = now
+	}
+}
+
+// WithHotCache 开启热点缓存，从 cacheBytes 中划出 ratio 比例的内存给热点缓存，ratio 必须在 (0, 1) 之间，
+// cacheBytes 为 0（不限制大小）时热点缓存同样不限制大小
+// 从远程节点获取的值以 rate 的概率写入热点缓存，避免热点 key 反复请求同一个节点
+// 所属节点要求不缓存的值不会写入，过期时间不晚于值在所属节点上的剩余有效期
+func WithHotCache(ratio, rate float64) GroupOption {
+	if ratio <= 0 || ratio >= 1 {
+		panic("热点缓存的比例必须在 (0, 1) 之间")
+	}
+	return func(g *Group) {
+		g.hotRatio = ratio
+		g.hotRate = rate
+	}
+}
+
+// WithEntryOverhead 设置每条缓存记录额外计入的内存开销，用于估算 key 和值以外的 map、链表等占用
+// 同时作用于主缓存和热点缓存，默认为 0
+func WithEntryOverhead(n int64) GroupOption {
+	return func(g *Group) {
+		g.mainCache.overhead = n
+		g.hotCache.overhead = n
+	}
+}
+
+// WithReplication 设置每个 key 的副本数，Get 时按顺序尝试 n 个副本节点，全部失败后才从数据源加载
+// writeReplicas 为 true 时 Set 会同时写入所有副本节点，否则只写入第一个节点
+// 需要 PeerPicker 实现 ReplicaPicker 接口
+func WithReplication(n int, writeReplicas bool) GroupOption {
+	return func(g *Group) {
+		g.replicas = n
+		g.replicaWrites = writeReplicas
+	}
+}
+
+// WithLogger 设置记录日志使用的 slog.Logger，默认为 slog.Default()，日志级别由它的 Handler 决定
+func WithLogger(logger *slog.Logger) GroupOption {
+	return func(g *Group) {
+		g.logger = logger
+	}
+}
+
+// WithRequestLogging 以 Debug 级别记录每次缓存命中，默认关闭
+func WithRequestLogging() GroupOption {
+	return func(g *Group) {
+		g.logRequests = true
+	}
+}
+
+// WithLoadTimeout 设置缓存未命中时加载一个 key 的超时时间，默认为 1 分钟
+// 同一个 key 的加载由所有等待者共享，不会因为某一个调用者的 ctx 结束而取消，
+// 调用者的 ctx 结束时只是自己提前返回，加载的结果仍会写入缓存
+func WithLoadTimeout(d time.Duration) GroupOption {
+	return func(g *Group) {
+		g.loadTimeout = d
+	}
+}
+
+// WithJanitor 启动后台协程，每隔 interval 清理一次已过期的缓存值
+func WithJanitor(interval time.Duration) GroupOption {
+	return func(g *Group) {
+		g.interval = interval
+	}
 }
 
 type Getter interface {
@@ -29,23 +156,96 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 	return f(key)
 }
 
+// ContextGetter 支持 context 的回调，Group 会优先调用 GetContext，便于取消和传递超时
+type ContextGetter interface {
+	Getter
+	GetContext(ctx context.Context, key string) ([]byte, error)
+}
+
+// ContextGetterFunc 接口型函数，实现了 ContextGetter 接口
+type ContextGetterFunc func(ctx context.Context, key string) ([]byte, error)
+
+func (f ContextGetterFunc) Get(key string) ([]byte, error) {
+	return f(context.Background(), key)
+}
+
+func (f ContextGetterFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
+
+// Meta 源数据附带的缓存元信息
+type Meta struct {
+	TTL     time.Duration // 覆盖 Group 的过期时间，为 0 时沿用 Group 的配置，为负数时永不过期
+	Version string        // 数据的版本号，例如 ETag
+	NoStore bool          // 为 true 时只返回数据，不写入缓存
+}
+
+// MetaGetter 在返回源数据的同时返回缓存元信息，Group 会优先调用 GetWithMeta
+type MetaGetter interface {
+	Getter
+	GetWithMeta(ctx context.Context, key string) ([]byte, Meta, error)
+}
+
+// MetaGetterFunc 接口型函数，实现了 MetaGetter 接口
+type MetaGetterFunc func(ctx context.Context, key string) ([]byte, Meta, error)
+
+func (f MetaGetterFunc) Get(key string) ([]byte, error) {
+	b, _, err := f(context.Background(), key)
+	return b, err
+}
+
+func (f MetaGetterFunc) GetWithMeta(ctx context.Context, key string) ([]byte, Meta, error) {
+	return f(ctx, key)
+}
+
 var (
 	mu     sync.RWMutex
 	groups = make(map[string]*Group)
 )
 
 // NewGroup 创建一个 Group 实例
-func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
+func NewGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption) *Group {
 	if getter == nil {
 		panic("getter 为空，缺少获取数据源的回调函数")
 	}
 	mu.Lock()
 	defer mu.Unlock()
 	g := &Group{
-		name:      name,
-		getter:    getter,
-		mainCache: cache{cacheBytes: cacheBytes},
-		loader:    &singleflight.Group{},
+		name:          name,
+		getter:        getter,
+		mainCache:     cache{cacheBytes: cacheBytes},
+		loader:        &singleflight.Group{},
+		now:           time.Now,
+		stop:          make(chan struct{}),
+		originLatency: newHistogram(),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.logger == nil {
+		g.logger = slog.Default()
+	}
+	if g.tracer == nil {
+		g.tracer = NoopTracer{}
+	}
+	if g.loadTimeout <= 0 {
+		g.loadTimeout = defaultLoadTimeout
+	}
+	g.logger = g.logger.With("group", name)
+	if g.hotRatio > 0 && cacheBytes > 0 {
+		// 主缓存和热点缓存都至少保留 1 字节，cacheBytes 为 0 表示不限制大小
+		hotBytes := min(max(int64(float64(cacheBytes)*g.hotRatio), 1), cacheBytes-1)
+		if hotBytes <= 0 {
+			g.logger.Warn("cache too small for a hot cache, disabled", "cacheBytes", cacheBytes)
+			g.hotRatio = 0
+		}
+		g.hotCache.cacheBytes = hotBytes
+		g.mainCache.cacheBytes -= hotBytes
+	}
+	g.mainCache.now = g.now
+	g.hotCache.now = g.now
+	if g.interval > 0 {
+		go g.janitor()
 	}
 	groups[name] = g
 	return g
@@ -59,16 +259,106 @@ func GetGroup(name string) *Group {
 	return g
 }
 
-// Get 从缓存中获取数据，如果不存在则调用 load 方法从数据源获取数据
+// Get 从缓存中获取数据，等价于 GetContext(context.Background(), key)
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext 从缓存中获取数据，如果不存在则调用 load 方法从数据源获取数据
+// ctx 中的值会被传递给远程节点和数据源；ctx 结束时立即返回 ctx.Err()，
+// 但同一个 key 的加载由所有调用者共享，会在 WithLoadTimeout 的限制内继续执行
+func (g *Group) GetContext(ctx context.Context, key string) (value ByteView, err error) {
 	if key == "" {
-		return ByteView{}, fmt.Errorf("key 字段为空")
+		return ByteView{}, errEmptyKey
 	}
-	if v, hit := g.mainCache.get(key); hit {
-		log.Println("[zcache] 缓存命中")
+	ctx, span := g.tracer.Start(ctx, spanGet, slog.String("group", g.name), slog.String("key", key))
+	defer func() { span.End(err) }()
+	g.stats.gets.Add(1)
+	if v, hit := g.lookupCache(key); hit {
+		g.stats.cacheHits.Add(1)
+		span.SetAttributes(slog.Bool("hit", true))
+		if g.logRequests {
+			g.logger.Debug("缓存命中", "key", key)
+		}
 		return v, nil
 	}
-	return g.load(key)
+	g.stats.loads.Add(1)
+	span.SetAttributes(slog.Bool("hit", false))
+	return g.load(ctx, key)
+}
+
+// lookupCache 依次查找主缓存和热点缓存
+func (g *Group) lookupCache(key string) (ByteView, bool) {
+	if v, hit := g.mainCache.get(key); hit {
+		return v, true
+	}
+	return g.hotCache.get(key)
+}
+
+// Set 将缓存值写入 key 所属的节点，并通知其他节点删除旧的副本
+func (g *Group) Set(ctx context.Context, key string, value []byte) error {
+	if key == "" {
+		return errEmptyKey
+	}
+	owners := g.pickOwners(key)
+	if !g.replicaWrites {
+		owners = owners[:1]
+	}
+	req := &pb.SetRequest{Group: g.name, Key: key, Value: value}
+	var errs []error
+	local := false
+	for i, owner := range owners {
+		if owner == nil {
+			g.setLocally(key, value)
+			local = true
+			continue
+		}
+		err := fmt.Errorf("远程节点不支持写入")
+		if updater, ok := owner.(PeerUpdater); ok {
+			err = updater.Set(ctx, req)
+		}
+		if err != nil {
+			// 第一个节点写入失败时直接返回，副本写入失败时继续
+			if i == 0 {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	if !local {
+		g.removeLocally(key)
+	}
+	errs = append(errs, g.broadcastRemove(ctx, key, owners...))
+	return errors.Join(errs...)
+}
+
+// Remove 删除 key 所属节点上的缓存值，并通知其他节点删除副本
+func (g *Group) Remove(ctx context.Context, key string) error {
+	if key == "" {
+		return errEmptyKey
+	}
+	owner, remote := g.pickPeer(key)
+	if remote {
+		updater, ok := owner.(PeerUpdater)
+		if !ok {
+			return fmt.Errorf("远程节点不支持删除")
+		}
+		if err := updater.Remove(ctx, &pb.RemoveRequest{Group: g.name, Key: key}); err != nil {
+			return err
+		}
+	}
+	g.removeLocally(key)
+	return g.broadcastRemove(ctx, key, owner)
+}
+
+// Invalidate 在数据源变化后清除所有节点上 key 的缓存值，下一次 Get 会重新加载
+// 与 Remove 不同，它不要求 key 所属的节点可用，会尽量通知每一个节点并汇总错误
+func (g *Group) Invalidate(ctx context.Context, key string) error {
+	if key == "" {
+		return errEmptyKey
+	}
+	g.removeLocally(key)
+	return g.broadcastRemove(ctx, key, nil)
 }
 
 // RegisterPeers 注册远程节点选择器
@@ -79,47 +369,258 @@ func (g *Group) RegisterPeers(peers PeerPicker) {
 	g.peers = peers
 }
 
-func (g *Group) load(key string) (value ByteView, err error) {
-	viewi, err := g.loader.Do(key, func() (interface{}, error) {
-		if g.peers != nil {
-			if peer, ok := g.peers.PickPeer(key); ok {
-				if value, err = g.getFromPeer(peer, key); err == nil {
-					return value, nil
-				}
-				log.Println("[zcache] 远程节点获取数据失败", err)
+// pickPeer 选择 key 所属的远程节点，key 属于本节点时返回 false
+func (g *Group) pickPeer(key string) (PeerGetter, bool) {
+	if g.peers == nil {
+		return nil, false
+	}
+	return g.peers.PickPeer(key)
+}
+
+// pickOwners 按优先级返回 key 的副本节点，本节点对应的位置为 nil，至少包含一个元素
+func (g *Group) pickOwners(key string) []PeerGetter {
+	if rp, ok := g.peers.(ReplicaPicker); ok && g.replicas > 1 {
+		if owners := rp.PickPeers(key, g.replicas); len(owners) > 0 {
+			return owners
+		}
+	}
+	peer, ok := g.pickPeer(key)
+	if !ok {
+		peer = nil
+	}
+	return []PeerGetter{peer}
+}
+
+func (g *Group) load(ctx context.Context, key string) (_ ByteView, err error) {
+	// span 覆盖等待 singleflight 的时间，shared 为 true 表示复用了其他调用者的结果
+	ctx, span := g.tracer.Start(ctx, spanLoad, slog.String("key", key))
+	var leader atomic.Bool // fn 在单独的协程中执行
+	defer func() {
+		span.SetAttributes(slog.Bool("shared", !leader.Load()))
+		span.End(err)
+	}()
+	viewi, err := g.loader.DoContext(ctx, key, func() (interface{}, error) {
+		leader.Store(true)
+		g.stats.loadsDeduped.Add(1)
+		g.stats.inFlight.Add(1)
+		defer g.stats.inFlight.Add(-1)
+		ctx, cancel := g.sharedContext(ctx)
+		defer cancel()
+		owners := g.pickOwners(key)
+		// 本节点是副本之一时，只尝试排在它前面的远程节点，之后直接在本地加载
+		if i := slices.Index(owners, nil); i >= 0 {
+			owners = owners[:i]
+		}
+		if forwarded(ctx) {
+			owners = nil
+		}
+		if len(owners) > 0 {
+			if value, err := g.getFromOwners(ctx, owners, key); err == nil {
+				return value, nil
 			}
 		}
-		return g.getLocally(key)
+		return g.getLocally(ctx, key)
 	})
-	if err == nil {
-		return viewi.(ByteView), nil
+	if err != nil {
+		return ByteView{}, err
 	}
-	return
+	return viewi.(ByteView), nil
 }
 
-func (g *Group) getLocally(key string) (ByteView, error) {
-	bytes, err := g.getter.Get(key)
+// forwardedKey ctx 中标记请求由其他节点转发而来
+type forwardedKey struct{}
+
+// withForwarded 标记请求由其他节点转发而来，Group 不会再把它转发给其他节点，未命中时直接在本地加载
+// 各节点的节点选择可能不一致（有界负载模式下各自统计的负载不同、节点列表正在变更），
+// 按本节点的选择再次转发会增加跳数，甚至在节点之间来回转发
+func withForwarded(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forwardedKey{}, true)
+}
+
+// forwarded 判断请求是否由其他节点转发而来
+func forwarded(ctx context.Context) bool {
+	v, _ := ctx.Value(forwardedKey{}).(bool)
+	return v
+}
+
+// sharedContext 返回 singleflight 共享加载使用的 ctx，保留 ctx 中的值（例如追踪上下文），
+// 但不随发起加载的调用者取消，超时时间为 loadTimeout
+func (g *Group) sharedContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), g.loadTimeout)
+}
+
+func (g *Group) getLocally(ctx context.Context, key string) (_ ByteView, err error) {
+	ctx, span := g.tracer.Start(ctx, spanGetLocally, slog.String("key", key))
+	defer func() { span.End(err) }()
+	start := time.Now()
+	bytes, meta, err := g.fetch(ctx, key)
+	g.originLatency.observe(time.Since(start))
 	if err != nil {
+		g.stats.localLoadErrs.Add(1)
 		return ByteView{}, err
 	}
-	value := ByteView{b: cloneBytes(bytes)}
-	g.populateCache(key, value)
+	g.stats.localLoads.Add(1)
+	value := ByteView{b: cloneBytes(bytes), version: meta.Version, noStore: meta.NoStore}
+	if !meta.NoStore {
+		value = g.populateCache(key, value, meta.TTL)
+	}
 	return value, nil
 }
 
-func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
+// setLocally 将其他节点推送的缓存值写入本节点
+func (g *Group) setLocally(key string, value []byte) {
+	g.populateCache(key, ByteView{b: cloneBytes(value)}, 0)
+}
+
+// handleSet 处理其他节点推送的缓存值，ctx 携带请求方的追踪上下文
+func (g *Group) handleSet(ctx context.Context, key string, value []byte) {
+	_, span := g.tracer.Start(ctx, spanSetLocally, slog.String("group", g.name), slog.String("key", key))
+	g.setLocally(key, value)
+	span.End(nil)
+}
+
+// handleRemove 处理其他节点发来的删除请求，ctx 携带请求方的追踪上下文
+func (g *Group) handleRemove(ctx context.Context, key string) {
+	_, span := g.tracer.Start(ctx, spanRemoveLocally, slog.String("group", g.name), slog.String("key", key))
+	g.removeLocally(key)
+	span.End(nil)
+}
+
+// removeLocally 删除本节点上的缓存值
+func (g *Group) removeLocally(key string) {
+	g.mainCache.remove(key)
+	g.hotCache.remove(key)
+}
+
+// Clear 清空本节点上的主缓存和热点缓存，不影响其他节点
+func (g *Group) Clear() {
+	g.mainCache.clear()
+	g.hotCache.clear()
+}
+
+// broadcastRemove 通知除 skip 外的其他节点删除 key，PeerPicker 未实现 PeerLister 时什么也不做
+func (g *Group) broadcastRemove(ctx context.Context, key string, skip ...PeerGetter) error {
+	lister, ok := g.peers.(PeerLister)
+	if !ok {
+		return nil
+	}
+	req := &pb.RemoveRequest{Group: g.name, Key: key}
+	var errs []error
+	for _, peer := range lister.Peers() {
+		if slices.Contains(skip, peer) {
+			continue
+		}
+		if updater, ok := peer.(PeerUpdater); ok {
+			if err := updater.Remove(ctx, req); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fetch 调用回调函数获取源数据，普通的 Getter 返回空的元信息
+func (g *Group) fetch(ctx context.Context, key string) ([]byte, Meta, error) {
+	switch getter := g.getter.(type) {
+	case MetaGetter:
+		return getter.GetWithMeta(ctx, key)
+	case ContextGetter:
+		bytes, err := getter.GetContext(ctx, key)
+		return bytes, Meta{}, err
+	}
+	bytes, err := g.getter.Get(key)
+	return bytes, Meta{}, err
+}
+
+func (g *Group) getFromPeer(ctx context.Context, peer PeerGetter, key string) (ByteView, error) {
+	ctx, span := g.tracer.Start(ctx, spanGetFromPeer, slog.String("key", key), slog.String("peer", peerName(peer)))
 	req := &pb.Request{
 		Group: g.name,
 		Key:   key,
 	}
 	res := &pb.Response{}
-	err := peer.Get(req, res)
+	start := time.Now()
+	err := peer.Get(ctx, req, res)
+	span.End(err)
+	g.peerLatency.get(peerName(peer)).observe(time.Since(start))
 	if err != nil {
+		g.stats.peerErrors.Add(1)
 		return ByteView{}, err
 	}
-	return ByteView{b: res.Value}, nil
+	g.stats.peerLoads.Add(1)
+	if g.latency != nil {
+		g.latency.observe(time.Since(start))
+	}
+	return g.populateHotCache(key, res.Value, res.GetMeta()), nil
 }
 
-func (g *Group) populateCache(key string, value ByteView) {
-	g.mainCache.add(key, value)
+// populateCache 写入缓存，ttl 为 0 时使用 Group 的过期时间配置，返回带有过期时间的值
+func (g *Group) populateCache(key string, value ByteView, ttl time.Duration) ByteView {
+	value.expire = g.expireAt(key, ttl)
+	g.mainCache.add(key, value, value.expire)
+	return value
+}
+
+// populateHotCache 以 hotRate 的概率将远程节点返回的值写入热点缓存
+// 过期时间不晚于所属节点上的剩余有效期；所属节点要求不缓存或没有返回元信息（旧版本的节点）时不写入
+func (g *Group) populateHotCache(key string, b []byte, meta *pb.EntryMeta) ByteView {
+	value := ByteView{b: b, version: meta.GetVersion(), noStore: meta.GetNoStore()}
+	if meta == nil || meta.NoStore || g.hotRatio <= 0 || rand.Float64() >= g.hotRate {
+		return value
+	}
+	value.expire = g.expireAt(key, 0)
+	if meta.TtlMs > 0 {
+		if owner := g.now().Add(time.Duration(meta.TtlMs) * time.Millisecond); value.expire.IsZero() || owner.Before(value.expire) {
+			value.expire = owner
+		}
+	}
+	g.hotCache.add(key, value, value.expire)
+	return value
+}
+
+// entryMeta 返回发给其他节点的缓存元信息
+func (g *Group) entryMeta(value ByteView) *pb.EntryMeta {
+	meta := &pb.EntryMeta{Version: value.version, NoStore: value.noStore}
+	if !value.expire.IsZero() {
+		// 已经到期的值仍然返回最小的有效期，避免被当作永不过期
+		meta.TtlMs = max(value.expire.Sub(g.now()).Milliseconds(), 1)
+	}
+	return meta
+}
+
+// expireAt 计算 key 的过期时间，零值表示永不过期
+// 优先级：源数据返回的 ttl > WithKeyTTL > WithTTL
+func (g *Group) expireAt(key string, ttl time.Duration) time.Time {
+	if ttl == 0 && g.keyTTL != nil {
+		ttl = g.keyTTL(key)
+	}
+	if ttl == 0 {
+		ttl = g.ttl
+	}
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return g.now().Add(ttl)
+}
+
+// janitor 定期清理过期缓存，直到 StopJanitor 被调用
+func (g *Group) janitor() {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			g.mainCache.removeExpired()
+			g.hotCache.removeExpired()
+		case <-g.stop:
+			return
+		}
+	}
+}
+
+// StopJanitor 停止后台清理协程，可以重复调用
+func (g *Group) StopJanitor() {
+	g.stopOnce.Do(func() {
+		close(g.stop)
+	})
 }
@@ -1,10 +1,16 @@
 package zcache
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	pb "zcache/zcachepb"
 )
 
 func TestGetter(t *testing.T) {
@@ -51,3 +57,518 @@ func TestGetGroup(t *testing.T) {
 		t.Fatalf("the value of unknown should be empty, but got %s", msg.String())
 	}
 }
+
+// fakeClock 可以手动拨动的时钟
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestEntryOverhead(t *testing.T) {
+	z := NewGroup("overhead", 100, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v"), nil
+		}),
+		WithEntryOverhead(40),
+	)
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := z.Get(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 每条记录占 1+1+40 字节，100 字节只能放下两条
+	if s := z.mainCache.stats(); s.Items != 2 || s.Bytes != 84 || s.Evictions != 1 {
+		t.Fatalf("expect the overhead to count against the budget, got %+v", s)
+	}
+}
+
+func TestGroupTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	loads := 0
+	z := NewGroup("ttl", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}),
+		WithTTL(time.Minute),
+		WithKeyTTL(func(key string) time.Duration {
+			if key == "forever" {
+				return -1
+			}
+			if key == "short" {
+				return time.Second
+			}
+			return 0
+		}),
+		WithClock(clock.Now),
+	)
+
+	for _, k := range []string{"short", "default", "forever"} {
+		if _, err := z.Get(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(time.Second)
+	_, _ = z.Get("short")
+	if loads != 4 {
+		t.Fatalf("short should have expired after 1s, loads=%d", loads)
+	}
+	_, _ = z.Get("default")
+	if loads != 4 {
+		t.Fatalf("default should still be cached, loads=%d", loads)
+	}
+	clock.Advance(time.Hour)
+	_, _ = z.Get("default")
+	if loads != 5 {
+		t.Fatalf("default should have expired after 1m, loads=%d", loads)
+	}
+	_, _ = z.Get("forever")
+	if loads != 5 {
+		t.Fatalf("forever should never expire, loads=%d", loads)
+	}
+}
+
+func TestJanitor(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	z := NewGroup("janitor", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}),
+		WithTTL(time.Second),
+		WithClock(clock.Now),
+		WithJanitor(time.Millisecond),
+	)
+	defer z.StopJanitor()
+
+	for _, k := range []string{"a", "b", "c"} {
+		if _, err := z.Get(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(time.Second)
+	deadline := time.Now().Add(time.Second)
+	for z.mainCache.len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("janitor did not reclaim expired entries, %d left", z.mainCache.len())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMetaGetter(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	loadCounts := make(map[string]int)
+	z := NewGroup("meta", 2<<10, MetaGetterFunc(
+		func(_ context.Context, key string) ([]byte, Meta, error) {
+			loadCounts[key]++
+			switch key {
+			case "volatile":
+				return []byte(key), Meta{NoStore: true}, nil
+			case "short":
+				return []byte(key), Meta{TTL: time.Second}, nil
+			}
+			return []byte(key), Meta{Version: "v1"}, nil
+		}),
+		WithTTL(time.Hour),
+		WithClock(clock.Now),
+	)
+
+	for i := 0; i < 2; i++ {
+		if _, err := z.Get("volatile"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if loadCounts["volatile"] != 2 {
+		t.Fatalf("NoStore value should not be cached, loads=%d", loadCounts["volatile"])
+	}
+
+	_, _ = z.Get("short")
+	_, _ = z.Get("versioned")
+	clock.Advance(time.Second)
+	_, _ = z.Get("short")
+	if loadCounts["short"] != 2 {
+		t.Fatalf("meta TTL should override group TTL, loads=%d", loadCounts["short"])
+	}
+	v, err := z.Get("versioned")
+	if err != nil || loadCounts["versioned"] != 1 {
+		t.Fatalf("versioned should still be cached, loads=%d err=%v", loadCounts["versioned"], err)
+	}
+	if v.Version() != "v1" {
+		t.Fatalf("expect version v1, got %q", v.Version())
+	}
+}
+
+type ctxKey struct{}
+
+func TestGetContext(t *testing.T) {
+	z := NewGroup("context", 2<<10, ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return []byte(fmt.Sprint(ctx.Value(ctxKey{}))), nil
+		}))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "trace-1")
+	if v, err := z.GetContext(ctx, "key"); err != nil || v.String() != "trace-1" {
+		t.Fatalf("context value not propagated to getter, got %q, %v", v.String(), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := z.GetContext(ctx, "canceled"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect context.Canceled, got %v", err)
+	}
+}
+
+func TestLoadOutlivesCaller(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var loads atomic.Int32
+	z := NewGroup("shared-load", 2<<10, ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			loads.Add(1)
+			close(started)
+			<-release
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return []byte(fmt.Sprint(ctx.Value(ctxKey{}))), nil
+		}))
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "trace-1"))
+	leader := make(chan error, 1)
+	go func() {
+		_, err := z.GetContext(ctx, "key")
+		leader <- err
+	}()
+	<-started
+	// 第一个调用者取消不影响共享的加载
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect Canceled for the caller that gave up, got %v", err)
+	}
+	// 加载在调用者返回后继续执行，结果写入缓存
+	close(release)
+	waitFor(t, "the shared load to finish", func() bool {
+		_, ok := z.mainCache.get("key")
+		return ok
+	})
+	if v, err := z.Get("key"); err != nil || v.String() != "trace-1" {
+		t.Fatalf("expect the load to keep the caller's values, got %q, %v", v.String(), err)
+	}
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("expect 1 load, got %d", n)
+	}
+}
+
+// legacyPeer 旧版不带 ctx 的 PeerGetter
+type legacyPeer struct{}
+
+func (legacyPeer) Get(in *pb.Request, out *pb.Response) error {
+	out.Value = []byte("legacy-" + in.GetKey())
+	return nil
+}
+
+func TestAdaptPeerGetter(t *testing.T) {
+	peer := AdaptPeerGetter(legacyPeer{})
+	out := &pb.Response{}
+	if err := peer.Get(context.Background(), &pb.Request{Key: "k"}, out); err != nil || string(out.Value) != "legacy-k" {
+		t.Fatalf("adapted Get = %q, %v", out.Value, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := peer.Get(ctx, &pb.Request{Key: "k"}, &pb.Response{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect Canceled, got %v", err)
+	}
+}
+
+// fakePeer 记录收到请求的远程节点
+type fakePeer struct {
+	mu      sync.Mutex
+	values  map[string][]byte
+	metas   map[string]*pb.EntryMeta // 返回的元信息，为 nil 的 key 模拟不返回元信息的旧版本节点
+	removed []string
+	gets    int
+}
+
+func (p *fakePeer) Get(_ context.Context, in *pb.Request, out *pb.Response) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gets++
+	v, ok := p.values[in.GetKey()]
+	if !ok {
+		return fmt.Errorf("%s not found", in.GetKey())
+	}
+	out.Value = v
+	out.Meta = &pb.EntryMeta{}
+	if meta, ok := p.metas[in.GetKey()]; ok {
+		out.Meta = meta
+	}
+	return nil
+}
+
+func (p *fakePeer) Set(_ context.Context, in *pb.SetRequest) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.values == nil {
+		p.values = make(map[string][]byte)
+	}
+	p.values[in.GetKey()] = in.GetValue()
+	return nil
+}
+
+func (p *fakePeer) Remove(_ context.Context, in *pb.RemoveRequest) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.values, in.GetKey())
+	p.removed = append(p.removed, in.GetKey())
+	return nil
+}
+
+// fakePicker 按 key 的首字母分配节点，"l" 开头的 key 属于本节点
+type fakePicker struct {
+	peers map[byte]*fakePeer
+}
+
+func (p *fakePicker) PickPeer(key string) (PeerGetter, bool) {
+	if peer, ok := p.peers[key[0]]; ok {
+		return peer, true
+	}
+	return nil, false
+}
+
+func (p *fakePicker) Peers() []PeerGetter {
+	peers := make([]PeerGetter, 0, len(p.peers))
+	for _, peer := range p.peers {
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+func TestSetRemoveInvalidate(t *testing.T) {
+	a, b := &fakePeer{}, &fakePeer{}
+	loads := 0
+	z := NewGroup("update", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte("origin"), nil
+		}))
+	z.RegisterPeers(&fakePicker{peers: map[byte]*fakePeer{'a': a, 'b': b}})
+	ctx := context.Background()
+
+	// key 属于远程节点 a：写入 a，并通知 b 删除副本
+	if err := z.Set(ctx, "akey", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if string(a.values["akey"]) != "v1" || len(a.removed) != 0 {
+		t.Fatalf("owner should store the value, got %q removed=%v", a.values["akey"], a.removed)
+	}
+	if !reflect.DeepEqual(b.removed, []string{"akey"}) {
+		t.Fatalf("other peers should be notified, got %v", b.removed)
+	}
+	if v, err := z.Get("akey"); err != nil || v.String() != "v1" {
+		t.Fatalf("expect v1 from owner, got %q, %v", v.String(), err)
+	}
+
+	// key 属于本节点：写入本地缓存，并通知所有远程节点
+	if err := z.Set(ctx, "lkey", []byte("local")); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := z.Get("lkey"); err != nil || v.String() != "local" || loads != 0 {
+		t.Fatalf("expect local value without loading, got %q, %v, loads=%d", v.String(), err, loads)
+	}
+
+	if err := z.Remove(ctx, "lkey"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := z.Get("lkey"); v.String() != "origin" || loads != 1 {
+		t.Fatalf("removed key should be reloaded, got %q, loads=%d", v.String(), loads)
+	}
+
+	if err := z.Remove(ctx, "akey"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := a.values["akey"]; ok {
+		t.Fatalf("owner should delete the key")
+	}
+
+	a.removed, b.removed = nil, nil
+	if err := z.Invalidate(ctx, "lkey"); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.removed) != 1 || len(b.removed) != 1 {
+		t.Fatalf("Invalidate should reach every peer, a=%v b=%v", a.removed, b.removed)
+	}
+	_, _ = z.Get("lkey")
+	if loads != 2 {
+		t.Fatalf("invalidated key should be reloaded, loads=%d", loads)
+	}
+}
+
+func TestHotCache(t *testing.T) {
+	a := &fakePeer{values: map[string][]byte{"ahot": []byte("hot")}}
+	z := NewGroup("hot", 1<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}),
+		WithHotCache(0.25, 1),
+	)
+	z.RegisterPeers(&fakePicker{peers: map[byte]*fakePeer{'a': a}})
+	if z.mainCache.cacheBytes != 768 || z.hotCache.cacheBytes != 256 {
+		t.Fatalf("expect 768/256 bytes split, got %d/%d", z.mainCache.cacheBytes, z.hotCache.cacheBytes)
+	}
+
+	for i := 0; i < 3; i++ {
+		if v, err := z.Get("ahot"); err != nil || v.String() != "hot" {
+			t.Fatalf("expect hot, got %q, %v", v.String(), err)
+		}
+	}
+	if a.gets != 1 {
+		t.Fatalf("hot key should be served from the hot cache, peer gets=%d", a.gets)
+	}
+	if _, ok := z.mainCache.get("ahot"); ok {
+		t.Fatalf("peer values must not be stored in the main cache")
+	}
+
+	// 收到失效通知后热点缓存同样被清除
+	z.removeLocally("ahot")
+	_, _ = z.Get("ahot")
+	if a.gets != 2 {
+		t.Fatalf("removed key should be fetched again, peer gets=%d", a.gets)
+	}
+}
+
+func TestHotCacheRatio(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	for _, ratio := range []float64{-0.5, 0, 1, 2} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expect WithHotCache(%v) to panic", ratio)
+				}
+			}()
+			WithHotCache(ratio, 1)
+		}()
+	}
+
+	// 两部分都至少保留 1 字节
+	z := NewGroup("hot-ratio", 100, getter, WithHotCache(0.999, 1))
+	if z.mainCache.cacheBytes != 1 || z.hotCache.cacheBytes != 99 {
+		t.Fatalf("expect 1/99 bytes split, got %d/%d", z.mainCache.cacheBytes, z.hotCache.cacheBytes)
+	}
+
+	// 不限制大小时热点缓存同样生效
+	a := &fakePeer{values: map[string][]byte{"ahot": []byte("hot")}}
+	z = NewGroup("hot-unbounded", 0, getter, WithHotCache(0.25, 1))
+	z.RegisterPeers(&fakePicker{peers: map[byte]*fakePeer{'a': a}})
+	for i := 0; i < 2; i++ {
+		if _, err := z.Get("ahot"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if z.mainCache.cacheBytes != 0 || a.gets != 1 {
+		t.Fatalf("unbounded group should still use the hot cache, main=%d peer gets=%d", z.mainCache.cacheBytes, a.gets)
+	}
+}
+
+func TestHotCacheMeta(t *testing.T) {
+	now := time.Now()
+	a := &fakePeer{
+		values: map[string][]byte{"anostore": []byte("x"), "attl": []byte("x"), "alegacy": []byte("x")},
+		metas: map[string]*pb.EntryMeta{
+			"anostore": {NoStore: true},
+			"attl":     {TtlMs: 1000, Version: "v3"},
+			"alegacy":  nil,
+		},
+	}
+	z := NewGroup("hot-meta", 1<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}),
+		WithHotCache(0.25, 1),
+		WithTTL(time.Hour),
+		WithClock(func() time.Time { return now }),
+	)
+	z.RegisterPeers(&fakePicker{peers: map[byte]*fakePeer{'a': a}})
+
+	for _, key := range []string{"anostore", "attl", "alegacy"} {
+		if _, err := z.Get(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := z.hotCache.get("anostore"); ok {
+		t.Fatalf("NoStore value from the owner should not be hot cached")
+	}
+	if _, ok := z.hotCache.get("alegacy"); ok {
+		t.Fatalf("value without metadata should not be hot cached")
+	}
+	if v, ok := z.hotCache.get("attl"); !ok || v.Version() != "v3" {
+		t.Fatalf("expect attl hot cached with version v3, got %q, %v", v.Version(), ok)
+	}
+	// 所属节点剩余的有效期短于本节点的 WithTTL
+	now = now.Add(2 * time.Second)
+	if _, ok := z.hotCache.get("attl"); ok {
+		t.Fatalf("hot entry should not outlive the owner's TTL")
+	}
+}
+
+// replicaPicker 所有 key 的副本依次为 owners
+type replicaPicker struct {
+	owners []PeerGetter
+}
+
+func (p *replicaPicker) PickPeer(key string) (PeerGetter, bool) {
+	return p.owners[0], p.owners[0] != nil
+}
+
+func (p *replicaPicker) PickPeers(key string, n int) []PeerGetter {
+	return p.owners[:min(n, len(p.owners))]
+}
+
+func TestReplication(t *testing.T) {
+	down := &fakePeer{}
+	replica := &fakePeer{values: map[string][]byte{"key": []byte("replica")}}
+	loads := 0
+	z := NewGroup("replication", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte("origin"), nil
+		}),
+		WithReplication(3, true),
+	)
+	z.RegisterPeers(&replicaPicker{owners: []PeerGetter{down, replica, nil}})
+
+	// 第一个副本不可用时从第二个副本获取，不会回源
+	if v, err := z.Get("key"); err != nil || v.String() != "replica" || loads != 0 {
+		t.Fatalf("expect value from replica, got %q, %v, loads=%d", v.String(), err, loads)
+	}
+	// 本节点也是副本之一，远程副本都失败时在本地加载
+	if v, err := z.Get("other"); err != nil || v.String() != "origin" || loads != 1 {
+		t.Fatalf("expect local load, got %q, %v, loads=%d", v.String(), err, loads)
+	}
+
+	if err := z.Set(context.Background(), "new", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if string(down.values["new"]) != "v" || string(replica.values["new"]) != "v" {
+		t.Fatalf("Set should write every replica")
+	}
+	if v, ok := z.mainCache.get("new"); !ok || v.String() != "v" {
+		t.Fatalf("local replica should be written too")
+	}
+}
@@ -74,8 +74,10 @@ func (x *Request) GetKey() string {
 }
 
 type Response struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Value         []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Value []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	// meta 所属节点上的缓存元信息，旧版本的节点不返回
+	Meta          *EntryMeta `protobuf:"bytes,2,opt,name=meta,proto3" json:"meta,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -117,6 +119,368 @@ func (x *Response) GetValue() []byte {
 	return nil
 }
 
+func (x *Response) GetMeta() *EntryMeta {
+	if x != nil {
+		return x.Meta
+	}
+	return nil
+}
+
+// EntryMeta 缓存值的元信息，其他节点据此决定是否以及多久缓存该值
+type EntryMeta struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Version string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	// ttl_ms 在所属节点上剩余的有效期（毫秒），为 0 表示永不过期
+	TtlMs int64 `protobuf:"varint,2,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	// no_store 为 true 时其他节点不应缓存该值
+	NoStore       bool `protobuf:"varint,3,opt,name=no_store,json=noStore,proto3" json:"no_store,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EntryMeta) Reset() {
+	*x = EntryMeta{}
+	mi := &file_zcachepb_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EntryMeta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EntryMeta) ProtoMessage() {}
+
+func (x *EntryMeta) ProtoReflect() protoreflect.Message {
+	mi := &file_zcachepb_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EntryMeta.ProtoReflect.Descriptor instead.
+func (*EntryMeta) Descriptor() ([]byte, []int) {
+	return file_zcachepb_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *EntryMeta) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *EntryMeta) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+func (x *EntryMeta) GetNoStore() bool {
+	if x != nil {
+		return x.NoStore
+	}
+	return false
+}
+
+// SetRequest 将缓存值写入目标节点
+type SetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetRequest) Reset() {
+	*x = SetRequest{}
+	mi := &file_zcachepb_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetRequest) ProtoMessage() {}
+
+func (x *SetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_zcachepb_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetRequest.ProtoReflect.Descriptor instead.
+func (*SetRequest) Descriptor() ([]byte, []int) {
+	return file_zcachepb_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SetRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *SetRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SetRequest) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+type SetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetResponse) Reset() {
+	*x = SetResponse{}
+	mi := &file_zcachepb_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetResponse) ProtoMessage() {}
+
+func (x *SetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_zcachepb_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetResponse.ProtoReflect.Descriptor instead.
+func (*SetResponse) Descriptor() ([]byte, []int) {
+	return file_zcachepb_proto_rawDescGZIP(), []int{4}
+}
+
+// RemoveRequest 删除目标节点上的缓存值
+type RemoveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveRequest) Reset() {
+	*x = RemoveRequest{}
+	mi := &file_zcachepb_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveRequest) ProtoMessage() {}
+
+func (x *RemoveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_zcachepb_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveRequest.ProtoReflect.Descriptor instead.
+func (*RemoveRequest) Descriptor() ([]byte, []int) {
+	return file_zcachepb_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RemoveRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *RemoveRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type RemoveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveResponse) Reset() {
+	*x = RemoveResponse{}
+	mi := &file_zcachepb_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveResponse) ProtoMessage() {}
+
+func (x *RemoveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_zcachepb_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveResponse.ProtoReflect.Descriptor instead.
+func (*RemoveResponse) Descriptor() ([]byte, []int) {
+	return file_zcachepb_proto_rawDescGZIP(), []int{6}
+}
+
+// MultiRequest 批量获取同一个 group 下的多个 key
+type MultiRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Keys          []string               `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MultiRequest) Reset() {
+	*x = MultiRequest{}
+	mi := &file_zcachepb_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MultiRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MultiRequest) ProtoMessage() {}
+
+func (x *MultiRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_zcachepb_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MultiRequest.ProtoReflect.Descriptor instead.
+func (*MultiRequest) Descriptor() ([]byte, []int) {
+	return file_zcachepb_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *MultiRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *MultiRequest) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+// MultiResponse 只包含获取成功的 key
+type MultiResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        map[string][]byte      `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Metas         map[string]*EntryMeta  `protobuf:"bytes,2,rep,name=metas,proto3" json:"metas,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MultiResponse) Reset() {
+	*x = MultiResponse{}
+	mi := &file_zcachepb_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MultiResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MultiResponse) ProtoMessage() {}
+
+func (x *MultiResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_zcachepb_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MultiResponse.ProtoReflect.Descriptor instead.
+func (*MultiResponse) Descriptor() ([]byte, []int) {
+	return file_zcachepb_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *MultiResponse) GetValues() map[string][]byte {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *MultiResponse) GetMetas() map[string]*EntryMeta {
+	if x != nil {
+		return x.Metas
+	}
+	return nil
+}
+
 var File_zcachepb_proto protoreflect.FileDescriptor
 
 const file_zcachepb_proto_rawDesc = "" +
@@ -124,12 +488,43 @@ const file_zcachepb_proto_rawDesc = "" +
 	"\x0ezcachepb.proto\x12\bzcachepb\"1\n" +
 	"\aRequest\x12\x14\n" +
 	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
-	"\x03key\x18\x02 \x01(\tR\x03key\" \n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\"I\n" +
 	"\bResponse\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\fR\x05value2:\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\x12'\n" +
+	"\x04meta\x18\x02 \x01(\v2\x13.zcachepb.EntryMetaR\x04meta\"W\n" +
+	"\tEntryMeta\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x15\n" +
+	"\x06ttl_ms\x18\x02 \x01(\x03R\x05ttlMs\x12\x19\n" +
+	"\bno_store\x18\x03 \x01(\bR\anoStore\"J\n" +
+	"\n" +
+	"SetRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\"\r\n" +
+	"\vSetResponse\"7\n" +
+	"\rRemoveRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\"\x10\n" +
+	"\x0eRemoveResponse\"8\n" +
+	"\fMultiRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x12\n" +
+	"\x04keys\x18\x02 \x03(\tR\x04keys\"\x90\x02\n" +
+	"\rMultiResponse\x12;\n" +
+	"\x06values\x18\x01 \x03(\v2#.zcachepb.MultiResponse.ValuesEntryR\x06values\x128\n" +
+	"\x05metas\x18\x02 \x03(\v2\".zcachepb.MultiResponse.MetasEntryR\x05metas\x1a9\n" +
+	"\vValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\x1aM\n" +
+	"\n" +
+	"MetasEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12)\n" +
+	"\x05value\x18\x02 \x01(\v2\x13.zcachepb.EntryMetaR\x05value:\x028\x012\xe8\x01\n" +
 	"\n" +
 	"GroupCache\x12,\n" +
-	"\x03Get\x12\x11.zcachepb.Request\x1a\x12.zcachepb.ResponseB\x1bZ\x19github.com/ZRZRING/zcacheb\x06proto3"
+	"\x03Get\x12\x11.zcachepb.Request\x1a\x12.zcachepb.Response\x122\n" +
+	"\x03Set\x12\x14.zcachepb.SetRequest\x1a\x15.zcachepb.SetResponse\x12;\n" +
+	"\x06Remove\x12\x17.zcachepb.RemoveRequest\x1a\x18.zcachepb.RemoveResponse\x12;\n" +
+	"\bGetMulti\x12\x16.zcachepb.MultiRequest\x1a\x17.zcachepb.MultiResponseB\x11Z\x0fzcache/zcachepbb\x06proto3"
 
 var (
 	file_zcachepb_proto_rawDescOnce sync.Once
@@ -143,19 +538,38 @@ func file_zcachepb_proto_rawDescGZIP() []byte {
 	return file_zcachepb_proto_rawDescData
 }
 
-var file_zcachepb_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_zcachepb_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_zcachepb_proto_goTypes = []any{
-	(*Request)(nil),  // 0: zcachepb.Request
-	(*Response)(nil), // 1: zcachepb.Response
+	(*Request)(nil),        // 0: zcachepb.Request
+	(*Response)(nil),       // 1: zcachepb.Response
+	(*EntryMeta)(nil),      // 2: zcachepb.EntryMeta
+	(*SetRequest)(nil),     // 3: zcachepb.SetRequest
+	(*SetResponse)(nil),    // 4: zcachepb.SetResponse
+	(*RemoveRequest)(nil),  // 5: zcachepb.RemoveRequest
+	(*RemoveResponse)(nil), // 6: zcachepb.RemoveResponse
+	(*MultiRequest)(nil),   // 7: zcachepb.MultiRequest
+	(*MultiResponse)(nil),  // 8: zcachepb.MultiResponse
+	nil,                    // 9: zcachepb.MultiResponse.ValuesEntry
+	nil,                    // 10: zcachepb.MultiResponse.MetasEntry
 }
 var file_zcachepb_proto_depIdxs = []int32{
-	0, // 0: zcachepb.GroupCache.GetPB:input_type -> zcachepb.Request
-	1, // 1: zcachepb.GroupCache.GetPB:output_type -> zcachepb.Response
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	2,  // 0: zcachepb.Response.meta:type_name -> zcachepb.EntryMeta
+	9,  // 1: zcachepb.MultiResponse.values:type_name -> zcachepb.MultiResponse.ValuesEntry
+	10, // 2: zcachepb.MultiResponse.metas:type_name -> zcachepb.MultiResponse.MetasEntry
+	2,  // 3: zcachepb.MultiResponse.MetasEntry.value:type_name -> zcachepb.EntryMeta
+	0,  // 4: zcachepb.GroupCache.Get:input_type -> zcachepb.Request
+	3,  // 5: zcachepb.GroupCache.Set:input_type -> zcachepb.SetRequest
+	5,  // 6: zcachepb.GroupCache.Remove:input_type -> zcachepb.RemoveRequest
+	7,  // 7: zcachepb.GroupCache.GetMulti:input_type -> zcachepb.MultiRequest
+	1,  // 8: zcachepb.GroupCache.Get:output_type -> zcachepb.Response
+	4,  // 9: zcachepb.GroupCache.Set:output_type -> zcachepb.SetResponse
+	6,  // 10: zcachepb.GroupCache.Remove:output_type -> zcachepb.RemoveResponse
+	8,  // 11: zcachepb.GroupCache.GetMulti:output_type -> zcachepb.MultiResponse
+	8,  // [8:12] is the sub-list for method output_type
+	4,  // [4:8] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_zcachepb_proto_init() }
@@ -169,7 +583,7 @@ func file_zcachepb_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_zcachepb_proto_rawDesc), len(file_zcachepb_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},